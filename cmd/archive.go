@@ -0,0 +1,54 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// archiveCmd represents the archive command
+var archiveCmd = &cobra.Command{
+	Use:   "archive <adr index>",
+	Short: "Move one ADR to the archive",
+	Long: `Move the selected ADR to the 'archive' subfolder of the ADR folder
+(keeping its category as subfolder). Links to the ADR in other ADRs (and
+in the configured 'docFolders') are updated.
+
+Archived ADRs keep their number, and are listed in the section 'Archived'
+of the TOC and under the heading 'Archived' in exports.
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changes are committed to git.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		logger.Debug("Command 'archive' called", "adr", args[0])
+
+		result, err := logic.ArchiveAdr(ctx, args[0])
+		if err != nil {
+			return wrapError(err, "Error archiving ADR %s", args[0])
+		}
+
+		printUpdateResult(result, false)
+
+		files := result.Changed
+		for _, r := range result.Renames {
+			files = append(files, r.From)
+		}
+		return commitChanges(cmd, fmt.Sprintf("docs(adr): archive ADR %s", args[0]), files)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(archiveCmd)
+
+	addCommitFlags(archiveCmd)
+}
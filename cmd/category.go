@@ -0,0 +1,99 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// categoryCmd represents the category command
+var categoryCmd = &cobra.Command{
+	Use:   "category [<category>]",
+	Short: "List or configure ADR categories",
+	Long: `ADRs can be organized in categories, which are subfolders of the ADR
+folder (see 'new --category'). By default, ADRs of all categories share one
+number sequence.
+
+Without argument, a table of the configured categories is printed.
+
+With a category name, that category is configured to use a number sequence
+of its own, with the prefix and number of digits given by the flags, e.g.
+'category security --prefix SEC- --digits 3' numbers ADRs in the folder
+'security' as SEC-001, SEC-002, ... With --shared, the category is switched
+back to the shared number sequence.`,
+	Args: cobra.MatchAll(cobra.RangeArgs(0, 1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		am, err := logic.OpenAdrManager(ctx)
+		if err != nil {
+			return wrapError(err, "Error opening ADR management")
+		}
+
+		if len(args) == 0 {
+			logger.Debug("Command 'category' called")
+
+			names := make([]string, 0)
+			for name := range am.Config.Categories {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			tbl := tablewriter.NewWriter(os.Stdout)
+			tbl.SetHeader([]string{"Category", "Prefix", "Digits"})
+			for _, name := range names {
+				prefix, digits, _ := am.Config.NumberingOfCategory(name)
+				tbl.Append([]string{name, prefix, strconv.Itoa(digits)})
+			}
+			tbl.Render()
+			return nil
+		}
+
+		prefix, _ := cmd.Flags().GetString("prefix")
+		digits, _ := cmd.Flags().GetInt("digits")
+		shared, _ := cmd.Flags().GetBool("shared")
+
+		logger.Debug("Command 'category' called", "category", args[0], "prefix", prefix, "digits", digits, "shared", shared)
+
+		unlock, err := am.Lock(ctx)
+		if err != nil {
+			return err
+		}
+
+		if am.Config.Categories == nil {
+			am.Config.Categories = make(map[string]data.CategoryConfiguration)
+		}
+		if shared {
+			delete(am.Config.Categories, args[0])
+		} else {
+			am.Config.Categories[args[0]] = data.CategoryConfiguration{Prefix: prefix, Digits: digits}
+		}
+
+		err = am.StoreConfiguration()
+		unlock()
+		if err != nil {
+			return wrapError(err, "Error storing ADR configuration")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(categoryCmd)
+
+	categoryCmd.Flags().StringP("prefix", "x", "", "prefix for the ADR numbers of the category")
+	categoryCmd.Flags().IntP("digits", "d", 0, "number of digits for the ADR numbers of the category (default: as configured globally)")
+	categoryCmd.Flags().BoolP("shared", "s", false, "use the shared number sequence for the category")
+}
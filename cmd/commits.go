@@ -0,0 +1,125 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
+	"github.com/spf13/cobra"
+)
+
+// commitsCmd represents the commits command
+var commitsCmd = &cobra.Command{
+	Use:   "commits [<adr index>]",
+	Short: "List commits implementing ADRs",
+	Long: fmt.Sprintf(`List the commits of the current git branch which implement the selected
+ADR, or, without argument, the implementing commits of each ADR.
+
+A commit implements an ADR if its message references it, by default with a
+trailer line like 'ADR: 0012' (several ADRs can be given comma-separated).
+Another regex can be configured with 'commitPattern' in the project
+configuration, or given with --pattern; its first group has to match the
+ADR id(s). Default: %s`, logic.DefaultCommitPattern),
+	Args: cobra.MatchAll(cobra.RangeArgs(0, 1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		pattern, _ := cmd.Flags().GetString("pattern")
+
+		if len(args) > 0 {
+			logger.Debug("Command 'commits' called", "adr", args[0], "pattern", pattern)
+
+			commits, err := logic.GetCommitsOfAdr(ctx, args[0], pattern)
+			if err != nil {
+				return wrapError(err, "Error reading commits of ADR %s", args[0])
+			}
+			if structuredOutput(cmd) {
+				am, err := logic.OpenAdrManager(ctx)
+				if err != nil {
+					return wrapError(err, "Error opening ADR management")
+				}
+				adrFile, err := am.GetAdrFilenameById(ctx, args[0])
+				if err != nil {
+					return wrapError(err, "Error finding ADR %s", args[0])
+				}
+				statuss, err := am.GetStatusFromListOfAdrFiles(ctx, []string{adrFile})
+				if err != nil {
+					return wrapError(err, "Error while loading status of ADR %s", args[0])
+				}
+				if len(statuss) != 1 {
+					return errors.New(fmt.Sprintf("Could not load status of ADR %s", args[0]))
+				}
+				return printOutput(cmd, commitsOutput{Adrs: []adrCommitsOutput{toAdrCommitsOutput(statuss[0], commits)}})
+			}
+			if len(commits) == 0 {
+				fmt.Printf("No commits implementing ADR #%s found.\n", args[0])
+			}
+			printCommits(commits)
+			return nil
+		}
+
+		logger.Debug("Command 'commits' called", "pattern", pattern)
+
+		am, err := logic.OpenAdrManager(ctx)
+		if err != nil {
+			return wrapError(err, "Error opening ADR management")
+		}
+		allAdrs, err := am.GetListOfAllAdrsStatus(ctx)
+		if err != nil {
+			return wrapError(err, "Error while loading ADR status")
+		}
+		commits, err := am.GetImplementingCommits(ctx, pattern)
+		if err != nil {
+			return wrapError(err, "Error reading implementing commits")
+		}
+		if structuredOutput(cmd) {
+			res := commitsOutput{Adrs: make([]adrCommitsOutput, 0)}
+			for _, adrst := range allAdrs {
+				if len(commits[adrst.Filename]) > 0 {
+					res.Adrs = append(res.Adrs, toAdrCommitsOutput(adrst, commits[adrst.Filename]))
+				}
+			}
+			return printOutput(cmd, res)
+		}
+		for _, adrst := range allAdrs {
+			if len(commits[adrst.Filename]) == 0 {
+				continue
+			}
+			fmt.Printf("ADR #%s: %s\n", adrst.Id, adrst.Title)
+			printCommits(commits[adrst.Filename])
+			fmt.Println()
+		}
+
+		return nil
+	},
+	Annotations: map[string]string{outputSchemaAnnotation: commitsSchema},
+}
+
+func toAdrCommitsOutput(adrst logic.AdrStatus, commits []utils.GitCommit) adrCommitsOutput {
+	res := adrCommitsOutput{Id: adrst.Id, Title: adrst.Title, File: filepath.ToSlash(adrst.Path), Commits: make([]commitOutput, 0, len(commits))}
+	for _, c := range commits {
+		res.Commits = append(res.Commits, toCommitOutput(c.Hash, c.Author, c.Date, c.Subject))
+	}
+
+	return res
+}
+
+func printCommits(commits []utils.GitCommit) {
+	for _, c := range commits {
+		fmt.Printf("%s  %s  %-20s  %s\n", c.Hash[:7], c.Date.Format("2006-01-02"), c.Author, c.Subject)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(commitsCmd)
+
+	commitsCmd.Flags().StringP("pattern", "p", "", "regex finding the implemented ADR id(s) in commit messages (default: as configured, or trailer 'ADR: <id>')")
+}
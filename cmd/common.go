@@ -1,34 +1,225 @@
 package cmd
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
 
 	"github.com/dukemarty/adr-go/logic"
 	"github.com/dukemarty/adr-go/utils"
 	"github.com/spf13/cobra"
 )
 
-var logger *log.Logger
+var logger *slog.Logger
 
-func initCommon(cmd *cobra.Command) {
-	verbose, _ := cmd.Flags().GetBool("verbose")
+// Set when a command was started, i.e. its arguments and flags are valid;
+// errors before are usage errors.
+var commandStarted bool
 
-	logger = utils.SetupLogger(verbose)
+// Error of a command, with the context in which the underlying error
+// occurred; the underlying error determines the exit code.
+type commandError struct {
+	context string
+	err     error
 }
 
-func loadAdrData(logger *log.Logger) (string, []logic.AdrStatus) {
-	am, err := logic.OpenAdrManager(logger)
+func (e *commandError) Error() string {
+	return e.context + ": " + e.err.Error()
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// Wrap the error into a commandError with the context, formatted like
+// fmt.Sprintf.
+func wrapError(err error, format string, args ...any) error {
+	return &commandError{context: fmt.Sprintf(format, args...), err: err}
+}
+
+// Error about invalid arguments or flags which can only be detected after
+// the command was started, e.g. because the valid values depend on the
+// project.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// Error of a command whose check failed (e.g. validate finding problems),
+// after the problems were printed; the message summarizes them.
+type checkFailedError struct {
+	message string
+}
+
+func (e *checkFailedError) Error() string {
+	return e.message
+}
+
+// Error of a command running another program (e.g. a plugin) which exited
+// with a non-zero code; adr-go exits with the same code, the program has
+// reported its errors already.
+type childExitError struct {
+	program string
+	code    int
+}
+
+func (e *childExitError) Error() string {
+	return fmt.Sprintf("'%s' exited with code %d", e.program, e.code)
+}
+
+func initCommon(cmd *cobra.Command) error {
+	verbosity, _ := cmd.Flags().GetCount("verbose")
+	logLevel, _ := cmd.Flags().GetString("log-level")
+	logFormat, _ := cmd.Flags().GetString("log-format")
+	logFile, _ := cmd.Flags().GetString("log-file")
+
+	if err := checkOutputFormat(cmd); err != nil {
+		return err
+	}
+
+	var err error
+	logger, err = utils.SetupLogger(utils.LogOptions{Verbosity: verbosity, Level: logLevel, Format: logFormat, File: logFile})
+	if err != nil {
+		return err
+	}
+	commandStarted = true
+	ctx := utils.WithLogger(cmd.Context(), logger)
+	cmd.SetContext(ctx)
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		utils.StartDryRun()
+	}
+	utils.SetJournalOperation(strings.Join(append([]string{cmd.Root().Name()}, os.Args[1:]...), " "))
+
+	adrLog, _ := cmd.Flags().GetString("log")
+	if len(adrLog) > 0 {
+		err := logic.SelectAdrLog(ctx, adrLog)
+		if err != nil {
+			return wrapError(err, "Error selecting ADR log '%s'", adrLog)
+		}
+	}
+
+	return nil
+}
+
+func loadAdrData(ctx context.Context) (string, []logic.AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	am, err := logic.OpenAdrManager(ctx)
+	if err != nil {
+		logger.Debug("Error opening ADR management", "err", err)
+		return "", []logic.AdrStatus{}, err
+	}
+
+	allAdrs, err := am.GetListOfAllAdrsStatus(ctx)
 	if err != nil {
-		logger.Printf("Error opening ADR management: %v\n", err)
-		return "", []logic.AdrStatus{}
+		logger.Debug("Error while loading ADR status'", "err", err)
+		return am.Config.Path, []logic.AdrStatus{}, wrapError(err, "Error while loading ADR status")
+	}
+	logger.Debug("Number of parsed and loaded ADRs", "count", len(allAdrs))
+
+	return am.Config.Path, allAdrs, nil
+}
+
+func loadAdrDataOfAllLogs(ctx context.Context) ([]logic.AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	allAdrs, err := logic.GetListOfAllAdrsStatusOfAllLogs(ctx)
+	if err != nil {
+		logger.Debug("Error while loading ADRs of all ADR logs", "err", err)
+		return []logic.AdrStatus{}, wrapError(err, "Error while loading ADRs of all ADR logs")
+	}
+	logger.Debug("Number of parsed and loaded ADRs of all ADR logs", "count", len(allAdrs))
+
+	return allAdrs, nil
+}
+
+func loadAdrDataOfAllProjects(ctx context.Context) ([]logic.AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	allAdrs, err := logic.GetListOfAllAdrsStatusOfAllProjects(ctx)
+	if err != nil {
+		logger.Debug("Error while loading ADRs of all projects", "err", err)
+		return []logic.AdrStatus{}, wrapError(err, "Error while loading ADRs of all projects")
+	}
+	logger.Debug("Number of parsed and loaded ADRs of all projects", "count", len(allAdrs))
+
+	return allAdrs, nil
+}
+
+// Add the flags controlling the commit of changed files to git to a command
+// which changes ADR files.
+func addCommitFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("commit", false, "commit the changed files to git (default: as configured with 'autoCommit' in the project configuration)")
+	cmd.Flags().Bool("no-commit", false, "do not commit the changed files to git, even if configured")
+	cmd.MarkFlagsMutuallyExclusive("commit", "no-commit")
+}
+
+// Decide by the commit flags and the project configuration if changed files
+// shall be committed.
+func shouldCommit(cmd *cobra.Command) bool {
+	commit, _ := cmd.Flags().GetBool("commit")
+	noCommit, _ := cmd.Flags().GetBool("no-commit")
+	if commit || noCommit {
+		return commit
+	}
+
+	am, err := logic.OpenAdrManager(cmd.Context())
+	if err != nil {
+		return false
+	}
+
+	return am.Config.AutoCommit
+}
+
+// Print the changes of a dry run, if one is active; to stderr with
+// structured output, which must be the only content of stdout.
+func printDryRun(cmd *cobra.Command) {
+	if !utils.IsDryRun() {
+		return
+	}
+
+	out := os.Stdout
+	if structuredOutput(cmd) {
+		out = os.Stderr
+	}
+	changes := utils.DescribeDryRun()
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "Dry run, no changes.")
+	} else {
+		fmt.Fprint(out, changes)
+	}
+}
+
+// Lock the selected ADR log against concurrent changes, for commands
+// changing it without a logic function doing so.
+//
+// Returns the function to release the lock, or an error.
+func lockAdrLog(ctx context.Context) (func(), error) {
+	am, err := logic.OpenAdrManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return am.Lock(ctx)
+}
+
+// Commit the changed files to git, if requested by flag or configuration.
+func commitChanges(cmd *cobra.Command, message string, files []string) error {
+	if !shouldCommit(cmd) {
+		return nil
 	}
 
-	allAdrs, err := am.GetListOfAllAdrsStatus(logger)
+	err := logic.CommitFiles(cmd.Context(), message, files)
 	if err != nil {
-		logger.Printf("Error while loading ADR status': %v\n", err)
-		return am.Config.Path, []logic.AdrStatus{}
+		return wrapError(err, "Error committing changes to git")
 	}
-	logger.Printf("Number of parsed and loaded ADRs: %d\n", len(allAdrs))
 
-	return am.Config.Path, allAdrs
+	return nil
 }
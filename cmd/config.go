@@ -17,22 +17,31 @@ var configCmd = &cobra.Command{
 	Long: `Create, change and/or print a user-specific configuration dotfile.
 	
 This file can point to a default editor to use for ADR editing, and it may
-contain the path to a central ADR store (support for this is not fully implemented
-yet).`,
+contain the path to a central ADR store.
+
+The central ADR store is a folder shared by several projects: projects are
+added to it with the 'register' command, and commands like 'list', 'search'
+and 'serve' can then work across all of them with --all-projects. Templates
+put into the store's 'templates' subfolder are available to all projects.`,
 	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
 
 		editor, _ := cmd.Flags().GetString("editor")
 		store, _ := cmd.Flags().GetString("store")
 
-		logger.Printf("Command 'config' called with editor='%s', and central adr store at '%s'.\n", editor, store)
+		logger.Debug("Command 'config' called", "editor", editor, "store", store)
 
 		// Basic function
 		config, err := data.LoadUserConfiguration()
-		if err != nil {
-			logger.Printf("Could not load user configuration: %v\n", err)
-			fmt.Println("New user configuration is created.")
+		created := err != nil
+		if created {
+			logger.Debug("Could not load user configuration", "err", err)
+			if !structuredOutput(cmd) {
+				fmt.Println("New user configuration is created.")
+			}
 			config = *data.NewUserConfiguration(editor, store)
 		} else {
 			if len(editor) > 0 {
@@ -42,10 +51,20 @@ yet).`,
 				config.CentralAdrStore = store
 			}
 		}
-		config.Store()
+		err = config.Store()
+		if err != nil {
+			return wrapError(err, "Error storing user configuration")
+		}
 
+		if structuredOutput(cmd) {
+			configPath, _ := data.UserConfigurationPath()
+			return printOutput(cmd, configOutput{File: configPath, Created: created, Editor: config.Editor, CentralStore: config.CentralAdrStore})
+		}
 		fmt.Printf("%v\n", config)
+
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: configSchema},
 }
 
 func init() {
@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
+	"github.com/spf13/cobra"
+)
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete <adr index>",
+	Short: "Delete one ADR",
+	Long: `Delete the selected ADR file. Links to the ADR in other ADRs (and in the
+configured 'docFolders') are removed, keeping the link texts, and the TOC is
+regenerated.
+
+As deleting should be done deliberately, --force is required; without it,
+the changes are only printed, as with --dry-run. Consider 'archive' or 'withdraw'
+instead, which keep the decision's history.
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changes are committed to git.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		force, _ := cmd.Flags().GetBool("force")
+
+		logger.Debug("Command 'delete' called", "adr", args[0], "force", force)
+
+		if !force {
+			utils.StartDryRun()
+		}
+
+		result, err := logic.DeleteAdr(ctx, args[0])
+		if err != nil {
+			return wrapError(err, "Error deleting ADR %s", args[0])
+		}
+
+		for _, f := range result.Removed {
+			fmt.Printf("Delete %s\n", f)
+		}
+		printUpdateResult(result, false)
+		if !force {
+			return &checkFailedError{message: "Nothing deleted, use --force to delete the ADR."}
+		}
+
+		return commitChanges(cmd, fmt.Sprintf("docs(adr): delete ADR %s", args[0]), result.Changed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().BoolP("force", "f", false, "really delete the ADR")
+	addCommitFlags(deleteCmd)
+}
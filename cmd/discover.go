@@ -0,0 +1,60 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// discoverCmd represents the discover command
+var discoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "List all ADR logs in the repository",
+	Long: `Search the repository containing the current directory for all ADR
+logs, i.e. all ADR configuration files, and print a table of them.
+
+The name of a log can be used with the --log flag to make any command work
+on that log, independent of the current directory. It is either the name
+stored in the log's configuration (see 'init --name'), or the name of the
+directory containing the configuration.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		logger.Debug("Command 'discover' called")
+
+		logs, err := logic.DiscoverAdrLogsOfRepository(ctx)
+		if err != nil {
+			return wrapError(err, "Error discovering ADR logs")
+		}
+
+		tbl := tablewriter.NewWriter(os.Stdout)
+		tbl.SetAutoWrapText(false)
+		tbl.SetHeader([]string{"Log", "Path", "Number of ADRs"})
+		for _, l := range logs {
+			count := "-"
+			if am, err := logic.OpenAdrManagerAt(ctx, l.BaseDir); err == nil {
+				if files, err := am.GetAllAdrFileNames(ctx); err == nil {
+					count = strconv.Itoa(len(files))
+				}
+			}
+			tbl.Append([]string{l.Name, l.BaseDir, count})
+		}
+		tbl.Render()
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(discoverCmd)
+}
@@ -18,20 +18,25 @@ var editCmd = &cobra.Command{
 	on command line, or the default editor defined in the project configuration
 	is used.`,
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 
 		editor, _ := cmd.Flags().GetString("editor")
 
-		logger.Printf("Command 'edit' called for ADR with index %s\n", args[0])
+		logger.Debug("Command 'edit' called", "adr", args[0])
 
-		adrFile, err := logic.GetAdrFilePathByIndexString(args[0], logger)
+		adrFile, err := logic.GetAdrFilePathByIndexString(ctx, args[0])
 		if err != nil {
-			logger.Fatalf("Error while trying to get ADR file for index %s: %v", args[0], err)
+			return wrapError(err, "Error while trying to get ADR file for index %s", args[0])
 		}
-		logger.Printf("Found file to edit: %s\n", adrFile)
+		logger.Debug("Found file to edit", "file", adrFile)
+
+		utils.EditFile(ctx, adrFile, editor, data.LoadEditor(ctx))
 
-		utils.EditFile(adrFile, editor, data.LoadEditor(logger), logger)
+		return nil
 	},
 }
 
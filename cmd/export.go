@@ -4,11 +4,18 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	adrexport "github.com/dukemarty/adr-go/export"
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 )
 
 // exportCmd represents the export command
@@ -19,42 +26,217 @@ var exportCmd = &cobra.Command{
 	are: %v
 
 	In JSON format, only a list of the most important information is returned,
-	the other formats contain the complete ADRs.
+	the other formats contain the complete ADRs. The format json-full contains
+	each ADR with its complete status history, its sections as markdown and as
+	plain text, its links, metadata and file path; its JSON schema is printed
+	by 'show schema', and 'import json' recreates the ADRs from it.
+
+	The formats yaml and xml contain the same as json-full (xml without the
+	configuration), ndjson each ADR of json-full on a line of its own.
 
 	The exports are printed on the console, to store directly into a file use
-	the -s/--store flag.`, adrexport.SupportedExporters),
-	ValidArgs: adrexport.SupportedExporters,
-	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	the -s/--store flag (file 'export.<extension>', e.g. 'export.md' for
+	markdown) or -f/--output-file with the path of the file. With --split,
+	each ADR is exported into a file of its own, named like the ADR file, in
+	the directory given by --output-file (default: 'export').
+
+	The flags --index, --status, --since and --tag select the ADRs to export;
+	an ADR must match all of them. Tags are given in a line 'Tags: ...' (tags
+	separated by commas) below the title of an ADR.
+
+	With the --all-logs flag, the ADRs of all ADR logs in the repository are
+	exported together, grouped by log.
+
+	The HTML export lists the commits implementing each ADR (see 'commits')
+	and the source locations referencing it (see 'refs') after the ADR.
+
+	Further formats are defined by the project: each Go text/template (file
+	'<format>.tmpl') in the folder configured as 'exporterTemplates' in the
+	project configuration, by default the subfolder 'exporters' of the ADR
+	folder, is rendered over the same data as the json-full export (fields
+	.Schema, .Configuration and .Adrs, with the fields of the JSON schema in
+	upper camel case, e.g. .Adrs[0].Sections). Besides the functions of
+	text/template, templates can use: %s.
+
+	Executables named 'adr-go-export-<format>' on PATH provide further
+	formats: they get the json-full export on stdin and print the export on
+	stdout.`, adrexport.SupportedExporters(), strings.Join(templateFunctionNames(), ", ")),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		registerAdditionalExporters(cmd.Context())
+		return adrexport.SupportedExporters(), cobra.ShellCompDirectiveNoFileComp
+	},
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		registerAdditionalExporters(ctx)
+		if !slices.Contains(adrexport.SupportedExporters(), strings.ToLower(args[0])) {
+			return &usageError{err: errors.New(fmt.Sprintf("invalid argument \"%s\" for \"%s\", must be one of: %v", args[0], cmd.CommandPath(), adrexport.SupportedExporters()))}
+		}
 
 		store, _ := cmd.Flags().GetBool("store")
+		allLogs, _ := cmd.Flags().GetBool("all-logs")
+		output, _ := cmd.Flags().GetString("output-file")
+		split, _ := cmd.Flags().GetBool("split")
+		selection, err := exportSelection(cmd)
+		if err != nil {
+			return &usageError{err: err}
+		}
 
-		logger.Printf("Command 'export' called with format '%s', store-to-file=%v.", args[0], store)
+		logger.Debug("Command 'export' called", "format", args[0], "store", store, "allLogs", allLogs, "outputFile", output, "split", split, "selection", selection)
 
-		dataPath, data := loadAdrData(logger)
+		var dataPath string
+		var data []logic.AdrStatus
+		if allLogs {
+			data, err = loadAdrDataOfAllLogs(ctx)
+		} else {
+			dataPath, data, err = loadAdrData(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		data = logic.SelectAdrs(ctx, data, selection)
+		if !allLogs {
+			if strings.ToLower(args[0]) == "html" {
+				err := logic.AddImplementingCommits(ctx, data)
+				if err != nil {
+					logger.Warn("Error while loading implementing commits", "err", err)
+				}
+				err = logic.AddReferences(ctx, data)
+				if err != nil {
+					logger.Warn("Error while scanning for ADR references", "err", err)
+				}
+			}
+		}
 
-		exporter, err := adrexport.CreateExporter(logger, args[0])
+		exporter, err := adrexport.CreateExporter(ctx, args[0])
 		if err != nil {
-			logger.Printf("Error when creating exporter: %v\n", err)
-			return
+			logger.Debug("Error when creating exporter", "err", err)
+			return wrapError(err, "Error when creating exporter")
 		}
 
-		exportData := exporter.Export(logger, data, dataPath)
-		if store {
-			filename := "export." + args[0]
-			err := os.WriteFile(filename, []byte(exportData), 0644)
+		if split {
+			if len(output) == 0 {
+				output = "export"
+			}
+			return writeSplitExport(ctx, exporter, data, dataPath, output, adrexport.ExporterFileExtension(args[0]))
+		}
+
+		exportData, err := exporter.Export(ctx, data, dataPath)
+		if err != nil {
+			logger.Debug("Error occurred creating the export", "err", err)
+			return wrapError(err, "Error occurred creating the export")
+		}
+		if store || len(output) > 0 {
+			filename := output
+			if len(filename) == 0 {
+				filename = "export." + adrexport.ExporterFileExtension(args[0])
+			}
+			err := writeExportFile(filename, exportData)
 			if err != nil {
-				logger.Printf("Error occurred writing the export file: %v\n", err)
+				logger.Debug("Error occurred writing the export file", "err", err)
+				return wrapError(err, "Error occurred writing the export file")
 			}
 		} else {
 			fmt.Println(exportData)
 		}
+
+		return nil
 	},
 }
 
+// Get the selection of the ADRs to export from the flags.
+func exportSelection(cmd *cobra.Command) (logic.AdrSelection, error) {
+	var res logic.AdrSelection
+	var err error
+	indexes, _ := cmd.Flags().GetString("index")
+	res.Status, _ = cmd.Flags().GetStringSlice("status")
+	res.Since, _ = cmd.Flags().GetString("since")
+	res.Tags, _ = cmd.Flags().GetStringSlice("tag")
+	if len(indexes) > 0 {
+		res.Indexes, err = logic.ParseIndexRanges(indexes)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, res.Validate()
+}
+
+// Export each ADR on its own into a file in the directory dir, named like
+// the ADR file with the extension ext. ADRs of other ADR logs or projects
+// are put into subfolders named by their origin.
+func writeSplitExport(ctx context.Context, exporter adrexport.AdrListExporter, data []logic.AdrStatus, dataPath string, dir string, ext string) error {
+	logger := utils.Logger(ctx)
+	for _, e := range data {
+		filename := filepath.Join(dir, e.Origin, strings.TrimSuffix(e.Filename, filepath.Ext(e.Filename))+"."+ext)
+		exportData, err := exporter.Export(ctx, []logic.AdrStatus{e}, dataPath)
+		if err != nil {
+			logger.Debug("Error occurred creating the export", "file", e.Path, "err", err)
+			return wrapError(err, "Error occurred creating the export of '%s'", e.Path)
+		}
+		err = writeExportFile(filename, exportData)
+		if err != nil {
+			logger.Debug("Error occurred writing the export file", "file", filename, "err", err)
+			return wrapError(err, "Error occurred writing the export file '%s'", filename)
+		}
+		logger.Info("Exported ADR", "file", filename)
+	}
+
+	return nil
+}
+
+// Write the export into the file, creating its folder if necessary.
+func writeExportFile(filename string, exportData string) error {
+	if dir := filepath.Dir(filename); dir != "." {
+		if err := utils.MkdirAll(dir); err != nil {
+			return err
+		}
+	}
+
+	return utils.WriteFile(filename, []byte(exportData), 0644)
+}
+
+// Register the exporter templates of the selected ADR log (if there is
+// one), and the external exporters on PATH; templates take precedence.
+func registerAdditionalExporters(ctx context.Context) {
+	logger := utils.Logger(ctx)
+	if am, err := logic.OpenAdrManager(ctx); err == nil {
+		err = adrexport.RegisterTemplateExporters(ctx, am.ExporterTemplatesDirectory())
+		if err != nil {
+			logger.Warn("Could not register exporter templates", "err", err)
+		}
+	}
+	adrexport.RegisterExternalExporters(ctx)
+}
+
+// Get the names of the functions available in exporter templates, sorted.
+func templateFunctionNames() []string {
+	res := make([]string, 0)
+	for name := range adrexport.TemplateFunctions() {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
 func init() {
 	rootCmd.AddCommand(exportCmd)
 
-	exportCmd.Flags().BoolP("store", "s", false, "store export to file instead of printing to console")
+	exportCmd.Flags().BoolP("store", "s", false, "store export to file 'export.<extension>' (e.g. 'export.md' for markdown) instead of printing to console")
+	exportCmd.Flags().BoolP("all-logs", "L", false, "export the ADRs of all ADR logs in the repository")
+	exportCmd.Flags().StringP("output-file", "f", "", "store export to this file (with --split: directory, default 'export') instead of printing to console")
+	exportCmd.Flags().Bool("split", false, "export each ADR into a file of its own, in the directory given by --output-file")
+	exportCmd.Flags().StringP("index", "i", "", "only export the ADRs with these indexes, e.g. '1-3,7,10-'")
+	exportCmd.Flags().StringSlice("status", nil, "only export the ADRs with one of these (current) status")
+	exportCmd.Flags().String("since", "", "only export the ADRs whose status changed on or after this date (2006-01-02)")
+	exportCmd.Flags().StringSlice("tag", nil, "only export the ADRs with one of these tags (metadata line 'Tags: ...')")
+	exportCmd.MarkFlagsMutuallyExclusive("store", "split")
 }
@@ -0,0 +1,76 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <adr index>",
+	Short: "Show git history of one ADR",
+	Long: `Show the revision history of the selected ADR, as recorded by git:
+its author and creation commit, followed by all commits which changed the
+ADR (newest first), each with its diff. Renames of the ADR file are followed.
+
+Requires the ADRs to be managed in a git repository, and the git binary to
+be available.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		withDiff, _ := cmd.Flags().GetBool("diff")
+
+		logger.Debug("Command 'history' called", "adr", args[0], "withDiff", withDiff)
+
+		history, err := logic.GetAdrHistory(ctx, args[0], withDiff)
+		if err != nil {
+			return wrapError(err, "Error reading history of ADR %s", args[0])
+		}
+
+		if structuredOutput(cmd) {
+			res := historyOutput{Adr: args[0], File: filepath.ToSlash(history.File), Commits: make([]historyCommitOutput, 0, len(history.Commits))}
+			if len(history.Commits) > 0 {
+				created := toCommitOutput(history.Created.Hash, history.Created.Author, history.Created.Date, history.Created.Subject)
+				res.Created = &created
+			}
+			for _, c := range history.Commits {
+				res.Commits = append(res.Commits, historyCommitOutput{commitOutput: toCommitOutput(c.Hash, c.Author, c.Date, c.Subject), Patch: c.Patch})
+			}
+			return printOutput(cmd, res)
+		}
+
+		fmt.Printf("ADR #%s: %s\n", args[0], history.File)
+		if len(history.Commits) == 0 {
+			fmt.Println("Not committed yet.")
+			return nil
+		}
+		fmt.Printf("Author:  %s <%s>\n", history.Created.Author, history.Created.Email)
+		fmt.Printf("Created: %s in %s\n\n", history.Created.Date.Format("2006-01-02"), history.Created.Hash[:7])
+
+		for _, c := range history.Commits {
+			fmt.Printf("%s  %s  %-20s  %s\n", c.Hash[:7], c.Date.Format("2006-01-02"), c.Author, c.Subject)
+			if withDiff && len(c.Patch) > 0 {
+				fmt.Printf("\n%s\n\n", c.Patch)
+			}
+		}
+
+		return nil
+	},
+	Annotations: map[string]string{outputSchemaAnnotation: historySchema},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().BoolP("diff", "d", true, "show the diff of each commit")
+}
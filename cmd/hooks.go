@@ -0,0 +1,67 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// hooksCmd represents the hooks command
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage git hooks for the ADR repository",
+	Long:  `Manage git hooks which validate the ADR repository, see 'hooks install'.`,
+}
+
+// hooksInstallCmd represents the hooks install command
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install git hooks validating the ADRs",
+	Long: `Install git hooks which validate the ADR repository (see 'validate'):
+
+  - pre-commit: validates the staged ADRs and the TOC, and rejects the
+    commit if there are problems; with --fix, filenames and TOC are fixed
+    (like 'update' does) and the fixed files are staged before
+  - pre-push: validates all ADRs and the TOC
+
+Existing hooks are kept, the validation is inserted at their start (right
+after the shebang line), so that it also runs if they end with 'exit 0'.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		fix, _ := cmd.Flags().GetBool("fix")
+		executable, _ := cmd.Flags().GetString("executable")
+		if len(executable) == 0 {
+			executable, _ = os.Executable()
+		}
+
+		logger.Debug("Command 'hooks install' called", "fix", fix, "executable", executable)
+
+		written, err := logic.InstallValidationHooks(ctx, executable, fix)
+		if err != nil {
+			return wrapError(err, "Error installing git hooks")
+		}
+		for _, f := range written {
+			fmt.Printf("Updated %s\n", f)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+	hooksCmd.AddCommand(hooksInstallCmd)
+
+	hooksInstallCmd.Flags().BoolP("fix", "f", false, "let the pre-commit hook fix filenames and TOC and stage the fixes")
+	hooksInstallCmd.Flags().StringP("executable", "e", "", "adr-go executable to be called by the hooks (default: path of the running executable)")
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// impactCmd represents the impact command
+var impactCmd = &cobra.Command{
+	Use:   "impact <adr index>",
+	Short: "Show what depends on one ADR",
+	Long: `Analyze what depends on the selected ADR, e.g. before deprecating it:
+
+  - all ADRs linking to it, e.g. amending it or being superseded by it,
+    and transitively all ADRs linking to those
+  - all other markdown files in the repository linking to the ADR's file
+  - all references to the ADR in source code (see 'refs')
+
+The result is printed as tree.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		withCode, _ := cmd.Flags().GetBool("code")
+
+		logger.Debug("Command 'impact' called", "adr", args[0], "withCode", withCode)
+
+		impact, err := logic.AnalyzeImpact(ctx, args[0], withCode)
+		if err != nil {
+			return wrapError(err, "Error analyzing impact of ADR %s", args[0])
+		}
+
+		if structuredOutput(cmd) {
+			return printOutput(cmd, impactOutput{Adr: toImpactNodeOutput(impact.Adr), Documents: impact.Documents, CodeReferences: impact.CodeReferences})
+		}
+
+		fmt.Printf("%s. %s [%s]\n", impact.Adr.Id, impact.Adr.Title, impact.Adr.Status)
+		printImpactTree(impact.Adr, "")
+		if len(impact.Documents) > 0 {
+			fmt.Println("\nLinked from documents:")
+			for _, d := range impact.Documents {
+				fmt.Println("  " + d)
+			}
+		}
+		if len(impact.CodeReferences) > 0 {
+			fmt.Println("\nReferenced from code:")
+			for _, r := range impact.CodeReferences {
+				fmt.Println("  " + r)
+			}
+		}
+
+		return nil
+	},
+	Annotations: map[string]string{outputSchemaAnnotation: impactSchema},
+}
+
+func printImpactTree(node *logic.ImpactNode, indent string) {
+	for i, child := range node.Dependents {
+		branch, childIndent := "├── ", indent+"│   "
+		if i == len(node.Dependents)-1 {
+			branch, childIndent = "└── ", indent+"    "
+		}
+		fmt.Printf("%s%s%s. %s [%s] (%s %s)\n", indent, branch, child.Id, child.Title, child.Status, child.Relation, node.Id)
+		printImpactTree(child, childIndent)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+
+	impactCmd.Flags().BoolP("code", "c", true, "include references from source code")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+// Formats of documents which can be imported.
+var supportedImportFormats = []string{"json"}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <format> <file>",
+	Short: "Import ADRs from an exported document",
+	Long: fmt.Sprintf(`Recreate ADRs from a document with their complete content, as written
+by 'export json-full' (see the JSON schema printed by 'show schema'). With
+'-' as file, the document is read from stdin. Allowed formats are: %v
+
+Each ADR is written to its filename relative to the ADR folder, and the TOC
+is regenerated. If an ADR has no complete markdown in the document, its file
+is assembled from title, metadata, status entries and sections. Existing
+ADR files are only overwritten with --force.
+
+If no ADR log is initialized in the current directory, one is initialized
+with the configuration contained in the document.
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changes are committed to git.`, supportedImportFormats),
+	Args: cobra.MatchAll(cobra.ExactArgs(2), func(cmd *cobra.Command, args []string) error {
+		if !slices.Contains(supportedImportFormats, args[0]) {
+			return errors.New(fmt.Sprintf("Unsupported import format '%s', must be one of: %v", args[0], supportedImportFormats))
+		}
+		return nil
+	}),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		force, _ := cmd.Flags().GetBool("force")
+
+		logger.Debug("Command 'import' called", "format", args[0], "file", args[1], "force", force)
+
+		var content []byte
+		var err error
+		if args[1] == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(args[1])
+		}
+		if err != nil {
+			return wrapError(err, "Could not read import file '%s'", args[1])
+		}
+
+		collection, err := logic.ReadAdrCollection(content, args[1])
+		if err != nil {
+			return wrapError(err, "Error reading import file")
+		}
+
+		files, err := logic.ImportAdrCollection(ctx, collection, force)
+		if err != nil {
+			return wrapError(err, "Error importing ADRs")
+		}
+		for _, f := range files {
+			fmt.Printf("Import %s\n", f)
+		}
+		logger.Info("Imported ADRs", "count", len(collection.Adrs))
+
+		return commitChanges(cmd, fmt.Sprintf("docs(adr): import %d ADR(s)", len(collection.Adrs)), files)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().BoolP("force", "f", false, "overwrite existing ADR files")
+	addCommitFlags(importCmd)
+}
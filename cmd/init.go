@@ -4,8 +4,6 @@ Copyright © 2023 Martin Loesch <development@martinloesch.net>
 package cmd
 
 import (
-	"log"
-
 	"github.com/dukemarty/adr-go/data"
 	"github.com/dukemarty/adr-go/logic"
 
@@ -41,33 +39,43 @@ var initCmd = &cobra.Command{
 	This involves setting up a folder for the ADRs, adding a configuration
 	file for the ADR tool, and adding initial ADRs.`,
 	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 
 		path, _ := cmd.Flags().GetString("path")
 		lang, _ := cmd.Flags().GetString("lang")
 		prefix, _ := cmd.Flags().GetString("prefix")
 		digits, _ := cmd.Flags().GetInt("digits")
 		template, _ := cmd.Flags().GetString("template")
+		name, _ := cmd.Flags().GetString("name")
 		newConfig := data.NewConfiguration(lang, path, prefix, digits, template)
+		newConfig.Name = name
 
-		logger.Printf("Command 'init' called with: %+v.\n", *newConfig)
+		logger.Debug("Command 'init' called", "newConfig", *newConfig)
 
 		// 1) Create config file and adr directory with standard templates
 		am := logic.NewAdrManager(*newConfig)
-		err := am.Init(logger)
+		err := am.Init(ctx)
 
 		if err != nil {
-			logger.Fatalf("Could not initialize ADRs: %v", err)
+			return wrapError(err, "Could not initialize ADRs")
 		}
-		logger.Println("ADRs initialized.")
+		logger.Info("ADRs initialized")
 
 		// 2) Create initial ADR
 		addFirst, _ := cmd.Flags().GetBool("addfirst")
 		if addFirst {
-			am.AddAdrWithContent("Record architecture decisions", firstAdr, logger)
-			log.Println("Initial ADR created.")
+			adrFile, err := am.AddAdrWithContent(ctx, "Record architecture decisions", firstAdr)
+			if err != nil {
+				return wrapError(err, "Could not create initial ADR")
+			}
+			logger.Info("Initial ADR created", "file", adrFile)
 		}
+
+		return nil
 	},
 }
 
@@ -80,4 +88,5 @@ func init() {
 	initCmd.Flags().BoolP("addfirst", "a", true, "add initial adr about using adr's")
 	initCmd.Flags().StringP("lang", "l", "en", "Language used, stored in config file")
 	initCmd.Flags().StringP("template", "t", "template-short.md", "template to use for new ADRs")
+	initCmd.Flags().StringP("name", "n", "", "name of the ADR log, to select it with --log (default: name of current directory)")
 }
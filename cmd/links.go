@@ -0,0 +1,141 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// linksCmd represents the links command
+var linksCmd = &cobra.Command{
+	Use:   "links",
+	Short: "Check and repair links in ADRs",
+	Long:  `Check the links in ADRs for dangling ones (see 'links check'), or repair links to renamed ADRs (see 'links fix').`,
+}
+
+// linksCheckCmd represents the links check command
+var linksCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report dangling links in ADRs",
+	Long: `Check all links (and images) in the ADRs: relative links must point to
+existing files, and anchors like '0002-foo.md#status' to existing headings.
+With --external, URLs are checked with a HEAD request as well, waiting at
+most the time given with --timeout.
+
+Each dangling link is printed with file and line, together with the repaired
+link if it points to a renamed ADR (see 'links fix'). The exit code is 1 if
+there are dangling links.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		external, _ := cmd.Flags().GetBool("external")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		logger.Debug("Command 'links check' called", "external", external, "timeout", timeout)
+
+		am, err := logic.OpenAdrManager(ctx)
+		if err != nil {
+			return wrapError(err, "Error opening ADR management")
+		}
+		broken, err := am.CheckLinks(ctx, external, timeout)
+		if err != nil {
+			return wrapError(err, "Error checking links")
+		}
+
+		if structuredOutput(cmd) {
+			res := linksCheckOutput{Broken: make([]linkOutput, 0, len(broken))}
+			for _, b := range broken {
+				res.Broken = append(res.Broken, toLinkOutput(b))
+			}
+			if err := printOutput(cmd, res); err != nil {
+				return err
+			}
+		} else {
+			for _, b := range broken {
+				fmt.Fprintln(os.Stderr, b.String())
+			}
+		}
+		if len(broken) > 0 {
+			return &checkFailedError{message: fmt.Sprintf("%d dangling link(s) found.", len(broken))}
+		}
+
+		return nil
+	},
+	Annotations: map[string]string{outputSchemaAnnotation: linksCheckSchema},
+}
+
+// linksFixCmd represents the links fix command
+var linksFixCmd = &cobra.Command{
+	Use:   "fix",
+	Short: "Repair links to renamed ADRs",
+	Long: `Repair all links in ADRs which point to ADRs which were renamed, e.g. by
+'update' after a change of the title. The ADR is found by its number.
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changed ADRs are committed to git.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		logger.Debug("Command 'links fix' called")
+
+		am, err := logic.OpenAdrManager(ctx)
+		if err != nil {
+			return wrapError(err, "Error opening ADR management")
+		}
+		fixed, err := am.FixLinks(ctx)
+		if err != nil {
+			return wrapError(err, "Error repairing links")
+		}
+
+		files := make([]string, 0)
+		for _, f := range fixed {
+			files = append(files, f.File)
+		}
+		if structuredOutput(cmd) {
+			res := linksFixOutput{Fixed: make([]linkOutput, 0, len(fixed))}
+			for _, f := range fixed {
+				res.Fixed = append(res.Fixed, toLinkOutput(f))
+			}
+			if err := printOutput(cmd, res); err != nil {
+				return err
+			}
+		} else {
+			for _, f := range fixed {
+				fmt.Printf("%s:%d: %s -> %s\n", f.File, f.Line, f.Destination, f.Fix)
+			}
+			if len(fixed) == 0 {
+				fmt.Println("No links to repair.")
+			}
+		}
+		if len(fixed) == 0 {
+			return nil
+		}
+
+		return commitChanges(cmd, fmt.Sprintf("docs(adr): repair %d link(s) to renamed ADRs", len(fixed)), files)
+	},
+	Annotations: map[string]string{outputSchemaAnnotation: linksFixSchema},
+}
+
+func init() {
+	rootCmd.AddCommand(linksCmd)
+	linksCmd.AddCommand(linksCheckCmd)
+	linksCmd.AddCommand(linksFixCmd)
+
+	linksCheckCmd.Flags().BoolP("external", "e", false, "check external URLs with a HEAD request")
+	linksCheckCmd.Flags().DurationP("timeout", "t", 5*time.Second, "timeout for checking an external URL")
+	addCommitFlags(linksFixCmd)
+}
@@ -4,8 +4,9 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/dukemarty/adr-go/logic"
@@ -19,6 +20,7 @@ var statusColors = map[string]tablewriter.Colors{
 	"DONE":       {tablewriter.Normal, tablewriter.FgHiGreenColor},
 	"DEPRECATED": {tablewriter.Normal, tablewriter.FgHiRedColor},
 	"SUPERSEDED": {tablewriter.Normal, tablewriter.FgHiYellowColor},
+	"WITHDRAWN":  {tablewriter.Normal, tablewriter.FgHiBlackColor},
 }
 
 // listCmd represents the list command
@@ -26,44 +28,109 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all ADRs",
 	Long: `Print a table of all ADRs (order by index) containing
-	their index, name, current status, and timestamp of last status
-	change.`,
-	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	their index, category, name, current status, and timestamp of last
+	status change.
 
-		logger.Println("Command 'list' called.")
+	With --commits, the number of commits implementing each ADR (see
+	'commits') is shown as additional column.
 
-		am, err := logic.OpenAdrManager(logger)
-		if err != nil {
-			logger.Fatalf("Error opening ADR management: %v\n", err)
+	With --output json|yaml, the ADRs are printed with schema
+	'adr-go/list/v1', including the implementing commits with --commits.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
 		}
+		ctx := cmd.Context()
+
+		allProjects, _ := cmd.Flags().GetBool("all-projects")
+		allLogs, _ := cmd.Flags().GetBool("all-logs")
+		withCommits, _ := cmd.Flags().GetBool("commits")
 
-		allAdrs, err := am.GetListOfAllAdrsStatus(logger)
-		if err != nil {
-			logger.Printf("Error while loading ADR status': %v\n", err)
+		logger.Debug("Command 'list' called", "allProjects", allProjects, "allLogs", allLogs, "withCommits", withCommits)
+
+		var allAdrs []logic.AdrStatus
+		var err error
+		if allProjects {
+			allAdrs, err = logic.GetListOfAllAdrsStatusOfAllProjects(ctx)
+			if err != nil {
+				return wrapError(err, "Error loading ADRs of all projects")
+			}
+			sort.SliceStable(allAdrs, func(i, j int) bool { return allAdrs[i].Origin < allAdrs[j].Origin })
+		} else if allLogs {
+			allAdrs, err = logic.GetListOfAllAdrsStatusOfAllLogs(ctx)
+			if err != nil {
+				return wrapError(err, "Error loading ADRs of all ADR logs")
+			}
+		} else {
+			am, err := logic.OpenAdrManager(ctx)
+			if err != nil {
+				return wrapError(err, "Error opening ADR management")
+			}
+
+			allAdrs, err = am.GetListOfAllAdrsStatus(ctx)
+			if err != nil {
+				logger.Warn("Error while loading ADR status'", "err", err)
+			}
+			if withCommits {
+				err = logic.AddImplementingCommits(ctx, allAdrs)
+				if err != nil {
+					logger.Warn("Error while loading implementing commits", "err", err)
+				}
+			}
+		}
+		logger.Debug("Number of parsed and loaded ADRs", "count", len(allAdrs))
+		if structuredOutput(cmd) {
+			return printOutput(cmd, toAdrListOutput(allAdrs))
 		}
-		logger.Printf("Number of parsed and loaded ADRs: %d\n", len(allAdrs))
 
 		tbl := tablewriter.NewWriter(os.Stdout)
 		tbl.SetAutoWrapText(false)
-		tbl.SetHeader([]string{"Index", "Decision", "Last modified date", "Last status"})
-		// to format index with fitting number of leading zeros, used data from config
-		fmtString := fmt.Sprintf("%%0%dd", am.Config.Digits)
+		header := []string{"Index", "Category", "Decision", "Last modified date", "Last status"}
+		if allProjects {
+			header = append([]string{"Project"}, header...)
+		} else if allLogs {
+			header = append([]string{"Log"}, header...)
+		}
+		if withCommits {
+			header = append(header, "Commits")
+		}
+		tbl.SetHeader(header)
 		for _, adrst := range allAdrs {
-			row := []string{fmt.Sprintf(fmtString, adrst.Index), adrst.Title, adrst.LastModified, adrst.LastStatus}
+			title := adrst.Title
+			if adrst.Archived {
+				title = "[archived] " + title
+			}
+			row := []string{adrst.Id, adrst.Category, title, adrst.LastModified, adrst.LastStatus}
 			statusColor := tablewriter.Colors{}
 			if val, present := statusColors[strings.ToUpper(adrst.LastStatus)]; present {
 				statusColor = val
 			}
-			colors := []tablewriter.Colors{{}, {}, {}, statusColor}
+			colors := []tablewriter.Colors{{}, {}, {}, {}, statusColor}
+			if withCommits {
+				row = append(row, strconv.Itoa(len(adrst.Commits)))
+				colors = append(colors, tablewriter.Colors{})
+			}
+			if allProjects || allLogs {
+				row = append([]string{adrst.Origin}, row...)
+				colors = append([]tablewriter.Colors{{}}, colors...)
+			}
 			tbl.Rich(row, colors)
 		}
 		tbl.Render()
 
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: listSchema},
 }
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().BoolP("all-projects", "A", false, "list the ADRs of all projects registered in the central ADR store")
+	listCmd.Flags().BoolP("all-logs", "L", false, "list the ADRs of all ADR logs in the repository")
+	listCmd.Flags().BoolP("commits", "c", false, "show the number of commits implementing each ADR")
+	listCmd.MarkFlagsMutuallyExclusive("all-projects", "all-logs")
+	listCmd.MarkFlagsMutuallyExclusive("all-projects", "commits")
+	listCmd.MarkFlagsMutuallyExclusive("all-logs", "commits")
 }
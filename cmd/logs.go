@@ -6,6 +6,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/dukemarty/adr-go/data"
 	"github.com/dukemarty/adr-go/logic"
@@ -18,22 +19,42 @@ var logsCmd = &cobra.Command{
 	Use:   "logs <adr index>",
 	Short: "List one ADR status logs",
 	Long: `This command lists in a table the different status the selected
-	ADR has had and the timestamp when the status was reached.`,
+	ADR has had and the timestamp when the status was reached.
+
+	With the --git flag, each status line is checked against git: the
+	commit which added the line and its author are shown, and status lines
+	whose date differs from the commit date, or which are not committed
+	yet, are marked.`,
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 
-		logger.Printf("Command 'logs' called for ADR #%s.\n", args[0])
+		logger.Debug("Command 'logs' called", "adr", args[0])
 
-		adrFile, err := logic.GetAdrFilePathByIndexString(args[0], logger)
+		adrFile, err := logic.GetAdrFilePathByIndexString(ctx, args[0])
 		if err != nil {
-			logger.Fatalf("Error while trying to get ADR file for index %s: %v", args[0], err)
+			return wrapError(err, "Error while trying to get ADR file for index %s", args[0])
 		}
 
-		status, err := data.ReadStatusEntries(logger, adrFile)
+		withGit, _ := cmd.Flags().GetBool("git")
+		if withGit {
+			return printStatusAttribution(cmd, args[0], adrFile)
+		}
+
+		status, err := data.ReadStatusEntries(ctx, adrFile)
 		if err != nil {
-			logger.Printf("Error reading status entries: %v\n", err)
-			return
+			return wrapError(err, "Error reading status entries")
+		}
+
+		if structuredOutput(cmd) {
+			res := logsOutput{Adr: args[0], File: filepath.ToSlash(adrFile), History: make([]statusEntryOutput, 0)}
+			for _, st := range status {
+				res.History = append(res.History, statusEntryOutput{Date: st.Date, Status: st.Status})
+			}
+			return printOutput(cmd, res)
 		}
 
 		fmt.Printf("ADR #%s: %s\n", args[0], adrFile)
@@ -44,9 +65,55 @@ var logsCmd = &cobra.Command{
 		}
 
 		tbl.Render()
+
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: logsSchema},
 }
 
 func init() {
 	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().BoolP("git", "g", false, "cross-check the status lines against the git commits which added them")
+}
+
+func printStatusAttribution(cmd *cobra.Command, adrIndex string, adrFile string) error {
+	attributions, err := logic.GetStatusAttribution(cmd.Context(), adrFile)
+	if err != nil {
+		return wrapError(err, "Error checking status entries against git")
+	}
+
+	if structuredOutput(cmd) {
+		res := logsOutput{Adr: adrIndex, File: filepath.ToSlash(adrFile), History: make([]statusEntryOutput, 0)}
+		for _, at := range attributions {
+			committed, dateMatches := at.Committed, at.DateMatches
+			entry := statusEntryOutput{Date: at.Date, Status: at.Status, Committed: &committed}
+			if committed {
+				commit := toCommitOutput(at.Commit.Hash, at.Commit.Author, at.Commit.Date, at.Commit.Summary)
+				entry.Commit = &commit
+				entry.DateMatches = &dateMatches
+			}
+			res.History = append(res.History, entry)
+		}
+		return printOutput(cmd, res)
+	}
+
+	fmt.Printf("ADR #%s: %s\n", adrIndex, adrFile)
+	tbl := tablewriter.NewWriter(os.Stdout)
+	tbl.SetHeader([]string{"Date of Change", "Status", "Commit", "Commit Date", "Author", "Check"})
+	for _, at := range attributions {
+		if !at.Committed {
+			tbl.Append([]string{at.Date, at.Status, "", "", "", "not committed"})
+			continue
+		}
+		check := "ok"
+		if !at.DateMatches {
+			check = "date differs"
+		}
+		tbl.Append([]string{at.Date, at.Status, at.Commit.Hash[:7], at.Commit.Date.Format("2006-01-02"), at.Commit.Author, check})
+	}
+
+	tbl.Render()
+
+	return nil
 }
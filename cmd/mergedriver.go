@@ -0,0 +1,114 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// mergeDriverCmd represents the git-merge-driver command
+var mergeDriverCmd = &cobra.Command{
+	Use:   "git-merge-driver <base> <current> <other> [<path>]",
+	Short: "Git merge driver for the ADR TOC",
+	Long: `Merge driver to be called by git (see 'install-merge-driver'), with the
+files of the common ancestor, the current and the other branch's version,
+and the path of the merged file.
+
+For the TOC file (README.md), the TOC is merged on the level of ADR entries
+instead of text lines: ADRs added or removed on either branch are added or
+removed, so that concurrent branches adding ADRs do not conflict. If the
+merged TOC contains duplicate ADR numbers, a warning is printed; use the
+'renumber' command to fix them after the merge.
+
+All other files are merged by the standard 'git merge-file'.`,
+	Args: cobra.MatchAll(cobra.RangeArgs(3, 4), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		mergedPath := args[1]
+		if len(args) > 3 {
+			mergedPath = args[3]
+		}
+
+		logger.Debug("Command 'git-merge-driver' called", "mergedPath", mergedPath)
+
+		if filepath.Base(mergedPath) != "README.md" {
+			gitCmd := exec.Command("git", "merge-file", args[1], args[0], args[2])
+			gitCmd.Stdout = os.Stdout
+			gitCmd.Stderr = os.Stderr
+			err := gitCmd.Run()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return &childExitError{program: "git merge-file", code: exitErr.ExitCode()}
+			} else if err != nil {
+				return wrapError(err, "Error running git merge-file")
+			}
+			return nil
+		}
+
+		duplicates, err := logic.MergeTocFiles(ctx, args[0], args[1], args[2])
+		if err != nil {
+			return wrapError(err, "Error merging TOC '%s'", mergedPath)
+		}
+		if len(duplicates) > 0 {
+			fmt.Fprintf(os.Stderr, "adr-go: duplicate ADR numbers after merge: %v\nadr-go: run 'adr-go renumber' to give the newer ADRs new numbers.\n", duplicates)
+		}
+
+		return nil
+	},
+}
+
+// installMergeDriverCmd represents the install-merge-driver command
+var installMergeDriverCmd = &cobra.Command{
+	Use:   "install-merge-driver",
+	Short: "Install git merge driver for the ADR TOC",
+	Long: `Configure the git repository to merge the ADR TOC (README.md) with
+adr-go's merge driver (see 'git-merge-driver') instead of a text merge.
+
+The driver is added to the repository's git config, the TOC file is
+assigned to it in .gitattributes (which should be committed, so that the
+assignment is shared), and a post-merge hook is added which reports
+duplicate ADR numbers after a merge.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		executable, _ := cmd.Flags().GetString("executable")
+		if len(executable) == 0 {
+			executable, _ = os.Executable()
+		}
+
+		logger.Debug("Command 'install-merge-driver' called", "executable", executable)
+
+		changed, err := logic.InstallMergeDriver(ctx, executable)
+		if err != nil {
+			return wrapError(err, "Error installing merge driver")
+		}
+		for _, f := range changed {
+			fmt.Printf("Updated %s\n", f)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mergeDriverCmd)
+	rootCmd.AddCommand(installMergeDriverCmd)
+
+	installMergeDriverCmd.Flags().StringP("executable", "e", "", "adr-go executable to be called by git (default: path of the running executable)")
+}
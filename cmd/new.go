@@ -4,6 +4,8 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"path/filepath"
+
 	"github.com/dukemarty/adr-go/data"
 	"github.com/dukemarty/adr-go/logic"
 	"github.com/dukemarty/adr-go/utils"
@@ -16,34 +18,76 @@ var newCmd = &cobra.Command{
 	Short: "Create new ADR",
 	Long: `Create a new ADR with a given title. The new ADR is automatically numbered,
 and a template file (either standard or a selected template), and then opened in
-an editor.`,
+an editor.
+
+With --category, the ADR is created in a subfolder of the ADR folder. If the
+category is configured with a number sequence of its own (see 'category'),
+it is numbered within the category, using the category's prefix.
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the new ADR and the TOC are committed to git.`,
 	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 
 		template, _ := cmd.Flags().GetString("template")
 		editor, _ := cmd.Flags().GetString("editor")
+		category, _ := cmd.Flags().GetString("category")
 
-		logger.Printf("Command 'new' called, with title '%s', explicit template?=%v ('%s')\n", args[0], len(template) > 0, template)
+		logger.Debug("Command 'new' called", "title", args[0], "template", template, "category", category)
 
-		am, err := logic.OpenAdrManager(logger)
+		am, err := logic.OpenAdrManager(ctx)
 		if err != nil {
-			logger.Fatalf("Error opening ADR management: %v\n", err)
+			return wrapError(err, "Error opening ADR management")
 		}
 
 		var adrFile string
 		if len(template) > 0 {
-			adrFile, err = am.AddAdrFromTemplate(args[0], template, logger)
+			adrFile, err = am.AddAdrFromTemplate(ctx, args[0], category, template)
 		} else {
-			adrFile, err = am.AddAdr(args[0], logger)
+			adrFile, err = am.AddAdr(ctx, args[0], category)
 		}
 		if err != nil {
-			logger.Fatalf("Error when creating new ADR: %v\n", err)
+			return wrapError(err, "Error when creating new ADR")
+		}
+		logger.Info("Created new ADR", "file", adrFile)
+
+		branchName := ""
+		if shouldCommit(cmd) {
+			branch, _ := cmd.Flags().GetBool("branch")
+			if branch || am.Config.ProposalBranches {
+				branchName, err = logic.CreateProposalBranch(ctx, adrFile)
+				if err != nil {
+					return wrapError(err, "Error creating branch for new ADR")
+				}
+				logger.Info("Created branch", "branch", branchName)
+			}
+			tocFile, _ := logic.GetTocFilePath(ctx)
+			if err := commitChanges(cmd, logic.CommitMessageForNewAdr(ctx, adrFile), []string{adrFile, tocFile}); err != nil {
+				return err
+			}
+		}
+
+		if !utils.IsDryRun() {
+			utils.EditFile(ctx, adrFile, editor, data.LoadEditor(ctx))
+		}
+
+		if structuredOutput(cmd) {
+			res := newOutput{Title: args[0], Category: category, File: filepath.ToSlash(adrFile), Branch: branchName}
+			if rel, err := filepath.Rel(am.AdrDirectory(), adrFile); err == nil {
+				if created, err := am.GetStatusFromListOfAdrFiles(ctx, []string{rel}); err == nil && len(created) == 1 {
+					res.Id, res.Index, res.Title = created[0].Id, created[0].Index, created[0].Title
+				}
+			}
+			return printOutput(cmd, res)
 		}
-		logger.Printf("Created new ADR as %s\n", adrFile)
 
-		utils.EditFile(adrFile, editor, data.LoadEditor(logger), logger)
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: newSchema},
 }
 
 func init() {
@@ -51,4 +95,7 @@ func init() {
 
 	newCmd.Flags().StringP("template", "t", "", "template file to use for the new ADR (located in ADR folder)")
 	newCmd.Flags().StringP("editor", "e", "", "path to editor executable for opening the ADR")
+	newCmd.Flags().StringP("category", "c", "", "category of the new ADR, i.e. subfolder of the ADR folder in which it is created")
+	newCmd.Flags().BoolP("branch", "b", false, "create a branch like 'adr/0012-title' for the new ADR before committing it (default: as configured with 'proposalBranches')")
+	addCommitFlags(newCmd)
 }
@@ -0,0 +1,359 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported values of the global flag --output.
+var supportedOutputFormats = []string{"text", "json", "yaml"}
+
+// Annotation of commands supporting structured output with --output; the
+// value is the schema of the command's result.
+const outputSchemaAnnotation = "outputSchema"
+
+// Schemas (name and version) of the results of the commands, see the
+// document 'Output' (command 'show'). A schema gets a new version whenever
+// a field is removed or changes its meaning; new fields may be added to an
+// existing version.
+const (
+	commitsSchema    = "adr-go/commits/v1"
+	configSchema     = "adr-go/config/v1"
+	historySchema    = "adr-go/history/v1"
+	impactSchema     = "adr-go/impact/v1"
+	linksCheckSchema = "adr-go/links-check/v1"
+	linksFixSchema   = "adr-go/links-fix/v1"
+	listSchema       = "adr-go/list/v1"
+	logsSchema       = "adr-go/logs/v1"
+	newSchema        = "adr-go/new/v1"
+	refsSchema       = "adr-go/refs/v1"
+	searchSchema     = "adr-go/search/v1"
+	showSchema       = "adr-go/show/v1"
+	statusSchema     = "adr-go/status/v1"
+	updateSchema     = "adr-go/update/v1"
+	validateSchema   = "adr-go/validate/v1"
+	versionSchema    = "adr-go/version/v1"
+)
+
+// Envelope of all structured output.
+type outputEnvelope struct {
+	Schema string `json:"schema" yaml:"schema"`
+	Result any    `json:"result" yaml:"result"`
+}
+
+// An ADR in the results of list and search.
+type adrOutput struct {
+	Origin       string         `json:"origin,omitempty" yaml:"origin,omitempty"`
+	Id           string         `json:"id" yaml:"id"`
+	Index        int            `json:"index" yaml:"index"`
+	Category     string         `json:"category,omitempty" yaml:"category,omitempty"`
+	Title        string         `json:"title" yaml:"title"`
+	File         string         `json:"file" yaml:"file"`
+	LastModified string         `json:"lastModified" yaml:"lastModified"`
+	LastStatus   string         `json:"lastStatus" yaml:"lastStatus"`
+	Archived     bool           `json:"archived" yaml:"archived"`
+	Commits      []commitOutput `json:"commits,omitempty" yaml:"commits,omitempty"`
+}
+
+type commitOutput struct {
+	Hash    string `json:"hash" yaml:"hash"`
+	Author  string `json:"author" yaml:"author"`
+	Date    string `json:"date" yaml:"date"`
+	Subject string `json:"subject,omitempty" yaml:"subject,omitempty"`
+}
+
+// Result of list (schema listSchema) and search (schema searchSchema).
+type adrListOutput struct {
+	Adrs []adrOutput `json:"adrs" yaml:"adrs"`
+}
+
+// Result of logs (schema logsSchema).
+type logsOutput struct {
+	Adr     string              `json:"adr" yaml:"adr"`
+	File    string              `json:"file" yaml:"file"`
+	History []statusEntryOutput `json:"history" yaml:"history"`
+}
+
+type statusEntryOutput struct {
+	Date   string `json:"date" yaml:"date"`
+	Status string `json:"status" yaml:"status"`
+	// Only set with --git.
+	Committed   *bool         `json:"committed,omitempty" yaml:"committed,omitempty"`
+	DateMatches *bool         `json:"dateMatches,omitempty" yaml:"dateMatches,omitempty"`
+	Commit      *commitOutput `json:"commit,omitempty" yaml:"commit,omitempty"`
+}
+
+// Result of new (schema newSchema).
+type newOutput struct {
+	Id       string `json:"id" yaml:"id"`
+	Index    int    `json:"index" yaml:"index"`
+	Category string `json:"category,omitempty" yaml:"category,omitempty"`
+	Title    string `json:"title" yaml:"title"`
+	File     string `json:"file" yaml:"file"`
+	Branch   string `json:"branch,omitempty" yaml:"branch,omitempty"`
+}
+
+// Result of status (schema statusSchema).
+type statusOutput struct {
+	Adr       string `json:"adr" yaml:"adr"`
+	File      string `json:"file" yaml:"file"`
+	OldStatus string `json:"oldStatus" yaml:"oldStatus"`
+	NewStatus string `json:"newStatus" yaml:"newStatus"`
+}
+
+// Result of update (schema updateSchema).
+type updateOutput struct {
+	Renames []renameOutput `json:"renames" yaml:"renames"`
+	Changed []string       `json:"changed" yaml:"changed"`
+	Removed []string       `json:"removed" yaml:"removed"`
+}
+
+type renameOutput struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// Result of version (schema versionSchema).
+type versionOutput struct {
+	Version  string `json:"version" yaml:"version"`
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+}
+
+// Result of config (schema configSchema).
+type configOutput struct {
+	File         string `json:"file" yaml:"file"`
+	Created      bool   `json:"created" yaml:"created"`
+	Editor       string `json:"editor" yaml:"editor"`
+	CentralStore string `json:"centralStore" yaml:"centralStore"`
+}
+
+// Result of commits (schema commitsSchema).
+type commitsOutput struct {
+	Adrs []adrCommitsOutput `json:"adrs" yaml:"adrs"`
+}
+
+type adrCommitsOutput struct {
+	Id      string         `json:"id" yaml:"id"`
+	Title   string         `json:"title" yaml:"title"`
+	File    string         `json:"file" yaml:"file"`
+	Commits []commitOutput `json:"commits" yaml:"commits"`
+}
+
+// Result of history (schema historySchema).
+type historyOutput struct {
+	Adr     string                `json:"adr" yaml:"adr"`
+	File    string                `json:"file" yaml:"file"`
+	Created *commitOutput         `json:"created,omitempty" yaml:"created,omitempty"`
+	Commits []historyCommitOutput `json:"commits" yaml:"commits"`
+}
+
+type historyCommitOutput struct {
+	commitOutput `yaml:",inline"`
+	// Only set with --diff.
+	Patch string `json:"patch,omitempty" yaml:"patch,omitempty"`
+}
+
+// Result of impact (schema impactSchema).
+type impactOutput struct {
+	Adr            impactNodeOutput `json:"adr" yaml:"adr"`
+	Documents      []string         `json:"documents" yaml:"documents"`
+	CodeReferences []string         `json:"codeReferences" yaml:"codeReferences"`
+}
+
+type impactNodeOutput struct {
+	Id     string `json:"id" yaml:"id"`
+	Title  string `json:"title" yaml:"title"`
+	Status string `json:"status" yaml:"status"`
+	File   string `json:"file" yaml:"file"`
+	// Empty for the analyzed ADR itself.
+	Relation   string             `json:"relation,omitempty" yaml:"relation,omitempty"`
+	Dependents []impactNodeOutput `json:"dependents,omitempty" yaml:"dependents,omitempty"`
+}
+
+// A link in the results of links check (schema linksCheckSchema) and links
+// fix (schema linksFixSchema).
+type linkOutput struct {
+	File        string `json:"file" yaml:"file"`
+	Line        int    `json:"line" yaml:"line"`
+	Destination string `json:"destination" yaml:"destination"`
+	Problem     string `json:"problem" yaml:"problem"`
+	Fix         string `json:"fix,omitempty" yaml:"fix,omitempty"`
+}
+
+type linksCheckOutput struct {
+	Broken []linkOutput `json:"broken" yaml:"broken"`
+}
+
+type linksFixOutput struct {
+	Fixed []linkOutput `json:"fixed" yaml:"fixed"`
+}
+
+// Result of refs (schema refsSchema).
+type refsOutput struct {
+	References []refOutput `json:"references" yaml:"references"`
+}
+
+type refOutput struct {
+	File    string `json:"file" yaml:"file"`
+	Line    int    `json:"line" yaml:"line"`
+	Id      string `json:"id" yaml:"id"`
+	Adr     string `json:"adr,omitempty" yaml:"adr,omitempty"`
+	Title   string `json:"title,omitempty" yaml:"title,omitempty"`
+	Status  string `json:"status,omitempty" yaml:"status,omitempty"`
+	Problem string `json:"problem,omitempty" yaml:"problem,omitempty"`
+}
+
+// Result of show (schema showSchema).
+type showOutput struct {
+	Documents []documentOutput `json:"documents" yaml:"documents"`
+}
+
+type documentOutput struct {
+	Name string `json:"name" yaml:"name"`
+	// Only set with --create.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Only set without --create.
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// Result of validate (schema validateSchema).
+type validateOutput struct {
+	Fixed  []renameOutput `json:"fixed" yaml:"fixed"`
+	Issues []issueOutput  `json:"issues" yaml:"issues"`
+}
+
+type issueOutput struct {
+	File    string `json:"file" yaml:"file"`
+	Line    int    `json:"line,omitempty" yaml:"line,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// Check the global flag --output: it must be one of supportedOutputFormats,
+// and a structured format is only allowed for commands annotated with their
+// output schema.
+func checkOutputFormat(cmd *cobra.Command) error {
+	format := outputFormat(cmd)
+	if !slices.Contains(supportedOutputFormats, format) {
+		return errors.New(fmt.Sprintf("Unsupported output format '%s', must be one of: %v", format, supportedOutputFormats))
+	}
+	if format != "text" && len(cmd.Annotations[outputSchemaAnnotation]) == 0 {
+		return errors.New(fmt.Sprintf("Command '%s' does not support --output %s", cmd.CommandPath(), format))
+	}
+
+	return nil
+}
+
+// Check if the command's result shall be printed as structured output
+// (JSON or YAML) instead of text.
+func structuredOutput(cmd *cobra.Command) bool {
+	format := outputFormat(cmd)
+
+	return format == "json" || format == "yaml"
+}
+
+// Get the format selected by the global flag --output.
+func outputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("output")
+
+	return format
+}
+
+// Print the result of the command in the structured format selected by
+// --output, in the envelope naming the command's schema.
+func printOutput(cmd *cobra.Command, result any) error {
+	format := outputFormat(cmd)
+	envelope := outputEnvelope{Schema: cmd.Annotations[outputSchemaAnnotation], Result: result}
+
+	var err error
+	switch format {
+	case "yaml":
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		err = enc.Encode(envelope)
+		if err == nil {
+			err = enc.Close()
+		}
+	default:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(envelope)
+	}
+	if err != nil {
+		return wrapError(err, "Error writing output")
+	}
+
+	return nil
+}
+
+func toAdrOutput(as logic.AdrStatus) adrOutput {
+	res := adrOutput{
+		Origin:       as.Origin,
+		Id:           as.Id,
+		Index:        as.Index,
+		Category:     as.Category,
+		Title:        as.Title,
+		File:         filepath.ToSlash(as.Path),
+		LastModified: as.LastModified,
+		LastStatus:   as.LastStatus,
+		Archived:     as.Archived,
+	}
+	for _, c := range as.Commits {
+		res.Commits = append(res.Commits, toCommitOutput(c.Hash, c.Author, c.Date, c.Subject))
+	}
+
+	return res
+}
+
+func toAdrListOutput(asl []logic.AdrStatus) adrListOutput {
+	res := adrListOutput{Adrs: make([]adrOutput, 0, len(asl))}
+	for _, as := range asl {
+		res.Adrs = append(res.Adrs, toAdrOutput(as))
+	}
+
+	return res
+}
+
+func toCommitOutput(hash string, author string, date time.Time, subject string) commitOutput {
+	return commitOutput{Hash: hash, Author: author, Date: date.Format(time.RFC3339), Subject: strings.TrimSpace(subject)}
+}
+
+func toImpactNodeOutput(node *logic.ImpactNode) impactNodeOutput {
+	res := impactNodeOutput{Id: node.Id, Title: node.Title, Status: node.Status, File: filepath.ToSlash(node.File), Relation: node.Relation}
+	for _, child := range node.Dependents {
+		res.Dependents = append(res.Dependents, toImpactNodeOutput(child))
+	}
+
+	return res
+}
+
+func toLinkOutput(link logic.BrokenLink) linkOutput {
+	return linkOutput{File: filepath.ToSlash(link.File), Line: link.Line, Destination: link.Destination, Problem: link.Problem, Fix: link.Fix}
+}
+
+func toUpdateOutput(result logic.UpdateResult) updateOutput {
+	res := updateOutput{Renames: make([]renameOutput, 0), Changed: make([]string, 0), Removed: make([]string, 0)}
+	for _, r := range result.Renames {
+		res.Renames = append(res.Renames, renameOutput{From: filepath.ToSlash(r.From), To: filepath.ToSlash(r.To)})
+	}
+	for _, c := range result.Changed {
+		res.Changed = append(res.Changed, filepath.ToSlash(c))
+	}
+	for _, r := range result.Removed {
+		res.Removed = append(res.Removed, filepath.ToSlash(r))
+	}
+
+	return res
+}
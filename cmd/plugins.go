@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	adrexport "github.com/dukemarty/adr-go/export"
+	"github.com/dukemarty/adr-go/utils"
+	"github.com/spf13/cobra"
+)
+
+// Prefix of the names of executables on PATH added as commands.
+const pluginPrefix = "adr-go-"
+
+// Add a command for each executable 'adr-go-<name>' on PATH, like git does
+// for 'git-<name>'. Built-in commands take precedence, and external
+// exporters ('adr-go-export-<format>', see export) are no commands.
+func addPluginCommands() {
+	builtin := map[string]bool{"help": true, "completion": true}
+	for _, c := range rootCmd.Commands() {
+		builtin[c.Name()] = true
+		for _, alias := range c.Aliases {
+			builtin[alias] = true
+		}
+	}
+
+	plugins := utils.FindExecutablesWithPrefix(pluginPrefix)
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		if !builtin[name] && !strings.HasPrefix(pluginPrefix+name, adrexport.ExternalExporterPrefix) && !strings.ContainsAny(name, " \t") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rootCmd.AddCommand(newPluginCommand(name, plugins[name]))
+	}
+}
+
+// Create the command running the plugin executable with all arguments
+// following the command's name.
+func newPluginCommand(name string, executable string) *cobra.Command {
+	return &cobra.Command{
+		Use:   name,
+		Short: "Plugin " + executable,
+		Long: `Command provided by the plugin ` + executable + `, which is called with all
+arguments following the command's name. The path of adr-go is passed to the
+plugin in the environment variable ` + utils.PluginExecutableEnv + `.`,
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initCommon(cmd); err != nil {
+				return err
+			}
+
+			logger.Debug("Plugin command called", "command", name, "executable", executable, "args", args)
+
+			pluginCmd := exec.Command(executable, args...)
+			pluginCmd.Stdin = os.Stdin
+			pluginCmd.Stdout = os.Stdout
+			pluginCmd.Stderr = os.Stderr
+			pluginCmd.Env = utils.PluginEnvironment()
+			err := pluginCmd.Run()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return &childExitError{program: executable, code: exitErr.ExitCode()}
+			} else if err != nil {
+				return wrapError(err, "Error running plugin '%s'", executable)
+			}
+
+			return nil
+		},
+	}
+}
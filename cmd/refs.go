@@ -0,0 +1,116 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// refsCmd represents the refs command
+var refsCmd = &cobra.Command{
+	Use:   "refs [<directory>]",
+	Short: "Find references to ADRs in source code",
+	Long: fmt.Sprintf(`Scan the source files below the directory (default: the project directory)
+for references to ADRs, e.g. comments like '// ADR-0007: why we retry here',
+and print which code locations cite which decisions.
+
+Files ignored by git are skipped, as well as the ADR folder itself. The
+files to scan can be restricted with --include and --exclude globs, or with
+'referenceInclude' and 'referenceExclude' in the project configuration. The
+regex for references (its first group matching the ADR id) can be given with
+--pattern or 'referencePattern'. Default: %s
+
+References to ADRs which do not exist, or which are deprecated,
+superseded, withdrawn or archived, are flagged as problems, and the exit
+code is 1 if there are any; so the command can be used as check in CI.`, logic.DefaultReferencePattern),
+	Args: cobra.MatchAll(cobra.RangeArgs(0, 1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		options := logic.ReferenceScanOptions{}
+		if len(args) > 0 {
+			options.Root = args[0]
+		}
+		options.Pattern, _ = cmd.Flags().GetString("pattern")
+		options.Include, _ = cmd.Flags().GetStringSlice("include")
+		options.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+		problemsOnly, _ := cmd.Flags().GetBool("problems")
+
+		logger.Debug("Command 'refs' called", "options", options, "problemsOnly", problemsOnly)
+
+		am, err := logic.OpenAdrManager(ctx)
+		if err != nil {
+			return wrapError(err, "Error opening ADR management")
+		}
+		refs, err := am.ScanReferences(ctx, options)
+		if err != nil {
+			return wrapError(err, "Error scanning for ADR references")
+		}
+
+		problems := 0
+		if structuredOutput(cmd) {
+			res := refsOutput{References: make([]refOutput, 0)}
+			for _, ref := range refs {
+				if len(ref.Problem) > 0 {
+					problems++
+				} else if problemsOnly {
+					continue
+				}
+				res.References = append(res.References, refOutput{File: filepath.ToSlash(ref.File), Line: ref.Line, Id: ref.Id, Adr: filepath.ToSlash(ref.AdrFilename), Title: ref.Title, Status: ref.Status, Problem: ref.Problem})
+			}
+			if err := printOutput(cmd, res); err != nil {
+				return err
+			}
+			return referenceProblems(problems)
+		}
+
+		tbl := tablewriter.NewWriter(os.Stdout)
+		tbl.SetAutoWrapText(false)
+		tbl.SetHeader([]string{"Location", "ADR", "Decision", "Last status", "Problem"})
+		for _, ref := range refs {
+			if len(ref.Problem) > 0 {
+				problems++
+			} else if problemsOnly {
+				continue
+			}
+			problemColor := tablewriter.Colors{}
+			if len(ref.Problem) > 0 {
+				problemColor = tablewriter.Colors{tablewriter.Normal, tablewriter.FgHiRedColor}
+			}
+			tbl.Rich([]string{ref.Location(), ref.Id, ref.Title, ref.Status, ref.Problem}, []tablewriter.Colors{{}, {}, {}, {}, problemColor})
+		}
+		tbl.Render()
+
+		return referenceProblems(problems)
+	},
+	Annotations: map[string]string{outputSchemaAnnotation: refsSchema},
+}
+
+// Get the error failing the check for the number of problematic
+// references, or nil if there are none.
+func referenceProblems(problems int) error {
+	if problems > 0 {
+		return &checkFailedError{message: fmt.Sprintf("%d problematic ADR reference(s) found.", problems)}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(refsCmd)
+
+	refsCmd.Flags().StringP("pattern", "p", "", "regex for references to ADRs, its first group matching the ADR id (default: as configured, or 'ADR-<number>')")
+	refsCmd.Flags().StringSliceP("include", "i", nil, "globs of the files to scan (default: as configured, or all files)")
+	refsCmd.Flags().StringSliceP("exclude", "x", nil, "globs of files not to scan (default: as configured)")
+	refsCmd.Flags().BoolP("problems", "P", false, "only print references with problems")
+}
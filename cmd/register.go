@@ -0,0 +1,48 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// registerCmd represents the register command
+var registerCmd = &cobra.Command{
+	Use:   "register",
+	Short: "Register project in the central ADR store",
+	Long: `Add the ADR project in the current directory to the central ADR
+store configured in the user configuration (see command 'config').
+
+All registered projects can then be listed, searched and served together
+using the --all-projects flag of the respective commands.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		name, _ := cmd.Flags().GetString("name")
+
+		logger.Debug("Command 'register' called", "name", name)
+
+		registeredName, err := logic.RegisterProject(ctx, name)
+		if err != nil {
+			return wrapError(err, "Error registering project in central ADR store")
+		}
+
+		fmt.Printf("Registered project as '%s'.\n", registeredName)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(registerCmd)
+
+	registerCmd.Flags().StringP("name", "n", "", "name of the project in the central store (default: name of current directory)")
+}
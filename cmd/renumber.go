@@ -0,0 +1,90 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// renumberCmd represents the renumber command
+var renumberCmd = &cobra.Command{
+	Use:   "renumber",
+	Short: "Fix duplicate ADR numbers",
+	Long: `Find ADR numbers used by several ADRs, e.g. after merging two branches
+which both added an ADR, and give the newer ADRs the next free numbers.
+Links to the renumbered ADRs in other ADRs and the configured doc folders
+are updated, and the TOC is regenerated.
+
+Which ADR is newer is determined by its creation commit in git, or else by
+its first status entry. Before renumbering, confirmation is asked for unless
+--yes is given. With --check, duplicates are only reported, and the exit
+code is 1 if there are any.
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changes are committed to git.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		check, _ := cmd.Flags().GetBool("check")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		logger.Debug("Command 'renumber' called", "check", check, "yes", yes)
+
+		am, err := logic.OpenAdrManager(ctx)
+		if err != nil {
+			return wrapError(err, "Error opening ADR management")
+		}
+		duplicates, err := am.FindDuplicateIndexes(ctx)
+		if err != nil {
+			return wrapError(err, "Error searching duplicate ADR numbers")
+		}
+		if len(duplicates) == 0 {
+			fmt.Println("No duplicate ADR numbers found.")
+			return nil
+		}
+
+		for _, d := range duplicates {
+			fmt.Printf("ADR number %d is used by %v, to be renumbered: %v\n", d.Index, d.Files, d.Newer())
+		}
+		if check {
+			return &checkFailedError{message: "Run 'adr-go renumber' to give the newer ADRs new numbers."}
+		}
+		if !yes {
+			confirmed := false
+			survey.AskOne(&survey.Confirm{Message: "Renumber the newer ADRs?"}, &confirmed)
+			if !confirmed {
+				return nil
+			}
+		}
+
+		result, err := logic.RenumberDuplicates(ctx)
+		if err != nil {
+			return wrapError(err, "Error renumbering ADRs")
+		}
+		files := result.Changed
+		for _, r := range result.Renames {
+			fmt.Printf("Renumbered %s -> %s\n", r.From, filepath.Base(r.To))
+			files = append(files, r.From)
+		}
+
+		return commitChanges(cmd, fmt.Sprintf("docs(adr): renumber %d ADR(s) with duplicate numbers", len(result.Renames)), files)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renumberCmd)
+
+	renumberCmd.Flags().BoolP("check", "c", false, "only report duplicate ADR numbers, exit with code 1 if there are any")
+	renumberCmd.Flags().BoolP("yes", "y", false, "renumber without asking for confirmation")
+	addCommitFlags(renumberCmd)
+}
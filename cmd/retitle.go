@@ -0,0 +1,63 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// retitleCmd represents the retitle command
+var retitleCmd = &cobra.Command{
+	Use:   "retitle <adr index> <new title>",
+	Short: "Change the title of one ADR",
+	Long: `Change the title of the selected ADR in one step: the heading is
+rewritten, the file is renamed to fit to the new title, links to the ADR in
+other ADRs (and in the configured 'docFolders') are updated, and the TOC is
+regenerated. If one of the changes fails, all of them are reverted.
+
+With --status-note, a status entry noting the title change is added to the
+ADR, keeping its current status.
+
+With --verbose-diff, the content changes are printed as unified diff (as
+done by the global --dry-run, which only prints the changes).
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changes are committed to git.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(2), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		statusNote, _ := cmd.Flags().GetBool("status-note")
+		verboseDiff, _ := cmd.Flags().GetBool("verbose-diff")
+
+		logger.Debug("Command 'retitle' called", "adr", args[0], "title", args[1], "statusNote", statusNote)
+
+		result, err := logic.RetitleAdr(ctx, args[0], args[1], statusNote)
+		if err != nil {
+			return wrapError(err, "Error changing title of ADR %s", args[0])
+		}
+
+		printUpdateResult(result, verboseDiff)
+
+		files := result.Changed
+		for _, r := range result.Renames {
+			files = append(files, r.From)
+		}
+		return commitChanges(cmd, fmt.Sprintf("docs(adr): retitle ADR %s to %s", args[0], args[1]), files)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retitleCmd)
+
+	retitleCmd.Flags().BoolP("status-note", "s", false, "add a status entry noting the title change")
+	retitleCmd.Flags().BoolP("verbose-diff", "d", false, "print the content changes as unified diff")
+	addCommitFlags(retitleCmd)
+}
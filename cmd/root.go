@@ -4,17 +4,59 @@ Copyright © 2023 Martin Loesch development@martinloesch.net
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
 	"github.com/spf13/cobra"
 )
 
+// Exit codes of the commands, by category of the error.
+const (
+	exitGeneralError   = 1
+	exitUsageError     = 2
+	exitNotInitialized = 3
+	exitAdrNotFound    = 4
+	exitParseError     = 5
+	exitInvalidStatus  = 6
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "adr-go",
 	Short: "A simple tool to handle ADRs.",
 	Long: `A simple tool to handle ADRs (Architecture Decision
-Records), a reimagining of the original adr-tools.`,
+Records), a reimagining of the original adr-tools.
+
+With --dry-run, commands changing files (like new, status, update or init)
+do not touch the disk; instead, the changes are printed as unified diff.
+
+With --output json or --output yaml, commands like list, logs, status or new
+print their result in a structured format with a versioned schema (see
+'show output'), instead of text.
+
+Log messages go to stderr (or the file given by --log-file), as text or
+JSON (--log-format). By default, only warnings and errors are logged; -v
+adds info, -vv debug messages, or --log-level selects the level explicitly.
+
+Executables named 'adr-go-<name>' on PATH are available as command <name>
+(unless adr-go has a command of that name), getting all further arguments.
+
+Errors are always printed to stderr; the exit code tells their category:
+  1  general error (also: failed checks of validate, refs, links check, ...)
+  2  invalid arguments or flags
+  3  no ADR log initialized
+  4  ADR not found
+  5  ADR file could not be parsed
+  6  invalid status`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		printDryRun(cmd)
+	},
 
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -24,9 +66,58 @@ Records), a reimagining of the original adr-tools.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	addPluginCommands()
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+
+	var usage *usageError
+	var checkFailed *checkFailedError
+	var childExit *childExitError
+	if errors.As(err, &childExit) {
+		os.Exit(exitCode(err))
+	}
+	if errors.As(err, &checkFailed) {
+		// the post run printing the changes is skipped for failing commands
+		printDryRun(cmd)
+	}
+	if !commandStarted || errors.As(err, &usage) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	} else {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// Get the exit code for the error returned by a command.
+func exitCode(err error) int {
+	var notInitialized *data.NotInitializedError
+	var notFound *data.AdrNotFoundError
+	var parseError *data.ParseError
+	var invalidStatus *data.InvalidStatusError
+	var usage *usageError
+	var checkFailed *checkFailedError
+	var childExit *childExitError
+
+	switch {
+	case !commandStarted, errors.As(err, &usage):
+		return exitUsageError
+	case errors.As(err, &checkFailed):
+		return exitGeneralError
+	case errors.As(err, &childExit) && childExit.code > 0:
+		return childExit.code
+	case errors.As(err, &notInitialized):
+		return exitNotInitialized
+	case errors.As(err, &notFound):
+		return exitAdrNotFound
+	case errors.As(err, &parseError):
+		return exitParseError
+	case errors.As(err, &invalidStatus):
+		return exitInvalidStatus
+	default:
+		return exitGeneralError
 	}
 }
 
@@ -36,7 +127,13 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.adr-go.yaml)")
-	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "activate verbose (debug) output")
+	rootCmd.PersistentFlags().CountP("verbose", "v", "log more messages: -v also info, -vv also debug messages")
+	rootCmd.PersistentFlags().String("log-level", "", fmt.Sprintf("level of logged messages (overrides -v), one of: %v", utils.SupportedLogLevels))
+	rootCmd.PersistentFlags().String("log-format", "text", fmt.Sprintf("format of logged messages, one of: %v", utils.SupportedLogFormats))
+	rootCmd.PersistentFlags().String("log-file", "", "append logged messages to this file instead of stderr")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "do not change any file, print the changes as unified diff instead")
+	rootCmd.PersistentFlags().StringP("output", "o", "text", fmt.Sprintf("format of the command's result, one of: %v; commands without a structured result reject json and yaml", supportedOutputFormats))
+	rootCmd.PersistentFlags().String("log", "", "name of the ADR log to work on, in a repository with several ADR logs (see command 'discover')")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
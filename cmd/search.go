@@ -4,8 +4,10 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"os"
 
+	"github.com/dukemarty/adr-go/data"
 	"github.com/dukemarty/adr-go/logic"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -18,42 +20,72 @@ var searchCmd = &cobra.Command{
 	Long: `Filter all ADRs by the presence of the provided keywords in their content,
 	and print the table of all found ADRs and their status.`,
 	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 
 		caseSensitive, _ := cmd.Flags().GetBool("casesensitive")
 
-		if caseSensitive {
-			logger.Printf("Command 'search' called case-sensitive with these keywords: %v\n", args)
+		logger.Debug("Command 'search' called", "keywords", args, "caseSensitive", caseSensitive)
+
+		allProjects, _ := cmd.Flags().GetBool("all-projects")
+
+		var statuss []logic.AdrStatus
+		var invalidKeyword *data.InvalidKeywordError
+		if allProjects {
+			var err error
+			statuss, err = logic.GetStatusOfAdrsFilteredInAllProjects(ctx, args, caseSensitive)
+			if errors.As(err, &invalidKeyword) {
+				return &usageError{err: err}
+			}
+			if err != nil {
+				return wrapError(err, "Error occured while filtering ADRs of all projects")
+			}
 		} else {
-			logger.Printf("Command 'search' called case-insensitive with these keywords: %v\n", args)
-		}
+			foundFiles, err := logic.GetAdrFilenamesFiltered(ctx, args, caseSensitive)
+			if errors.As(err, &invalidKeyword) {
+				return &usageError{err: err}
+			}
+			if err != nil {
+				return wrapError(err, "Error occured while filtering files")
+			}
 
-		foundFiles, err := logic.GetAdrFilenamesFiltered(args, caseSensitive, logger)
-		if err != nil {
-			logger.Printf("Error occured while filtering files: %v\n", err)
-			return
+			statuss, err = logic.GetStatusFromListOfAdrFiles(ctx, foundFiles)
+			if err != nil {
+				return wrapError(err, "Error loading status' from ADR files")
+			}
 		}
 
-		statuss, err := logic.GetStatusFromListOfAdrFiles(foundFiles, logger)
-		if err != nil {
-			logger.Printf("Error loading status' from ADR files: %v\n", err)
-			return
+		if structuredOutput(cmd) {
+			return printOutput(cmd, toAdrListOutput(statuss))
 		}
 
 		tbl := tablewriter.NewWriter(os.Stdout)
 		tbl.SetAutoWrapText(false)
-		tbl.SetHeader([]string{"Filename", "Last status"})
+		if allProjects {
+			tbl.SetHeader([]string{"Project", "Filename", "Last status"})
+		} else {
+			tbl.SetHeader([]string{"Filename", "Last status"})
+		}
 		for _, adrst := range statuss {
-			tbl.Append([]string{adrst.Filename, adrst.LastStatus})
+			if allProjects {
+				tbl.Append([]string{adrst.Origin, adrst.Filename, adrst.LastStatus})
+			} else {
+				tbl.Append([]string{adrst.Filename, adrst.LastStatus})
+			}
 		}
 		tbl.Render()
 
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: searchSchema},
 }
 
 func init() {
 	rootCmd.AddCommand(searchCmd)
 
 	searchCmd.Flags().BoolP("casesensitive", "c", false, "flag to activate case-sensitive search")
+	searchCmd.Flags().BoolP("all-projects", "A", false, "search the ADRs of all projects registered in the central ADR store")
 }
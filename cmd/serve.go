@@ -4,9 +4,11 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
@@ -14,9 +16,16 @@ import (
 
 	adrexport "github.com/dukemarty/adr-go/export"
 	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
 	"github.com/spf13/cobra"
 )
 
+// serve the ADRs of all projects registered in the central ADR store
+var serveAllProjects = false
+
+// serve the ADRs of all ADR logs in the repository
+var serveAllLogs = false
+
 // serveCmd represents the serve command
 var serveCmd = &cobra.Command{
 	Use:   "serve",
@@ -26,15 +35,26 @@ var serveCmd = &cobra.Command{
 Basically, the page which would be exported as html is served via http.
 	
 Additionally, endpoints are provided to get a list of the available ADRs
-(at 'URL/adrs') and to fetch a single ADR in its original format (at
-'URL/adr/INDEX').`,
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+(at 'URL/adrs', including the source locations referencing each ADR, see
+'refs') and to fetch a single ADR in its original format (at
+'URL/adr/INDEX').
+
+With the --all-projects flag, one combined site of all projects registered
+in the central ADR store is served. Single ADRs are then available at
+'URL/adr/PROJECT/INDEX'. Likewise, the --all-logs flag serves all ADR logs
+in the repository, with single ADRs at 'URL/adr/LOG/INDEX'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 
 		address, _ := cmd.Flags().GetString("address")
 		port, _ := cmd.Flags().GetUint16("port")
+		serveAllProjects, _ = cmd.Flags().GetBool("all-projects")
+		serveAllLogs, _ = cmd.Flags().GetBool("all-logs")
 
-		logger.Println("Command 'serve' called.")
+		logger.Debug("Command 'serve' called", "allProjects", serveAllProjects, "allLogs", serveAllLogs)
 
 		http.HandleFunc("/", showMainSiteHandler)
 		http.HandleFunc("/adr/", adrHandler)
@@ -42,7 +62,12 @@ Additionally, endpoints are provided to get a list of the available ADRs
 
 		serveUrl := fmt.Sprintf("%s:%d", address, port)
 		fmt.Printf("Serving at http://%s\n", serveUrl)
-		log.Fatal(http.ListenAndServe(serveUrl, nil))
+		// Requests carry the command's context, and with it the logger.
+		server := &http.Server{
+			Addr:        serveUrl,
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		}
+		return server.ListenAndServe()
 	},
 }
 
@@ -51,36 +76,52 @@ func init() {
 
 	serveCmd.Flags().StringP("address", "a", "localhost", "adress (IP or localhost) to which to bind the server")
 	serveCmd.Flags().Uint16P("port", "p", 8080, "port to which to bind the server")
+	serveCmd.Flags().BoolP("all-projects", "A", false, "serve the ADRs of all projects registered in the central ADR store")
+	serveCmd.Flags().BoolP("all-logs", "L", false, "serve the ADRs of all ADR logs in the repository")
+	serveCmd.MarkFlagsMutuallyExclusive("all-projects", "all-logs")
 }
 
 func showMainSiteHandler(w http.ResponseWriter, r *http.Request) {
-	dataPath, data := loadAdrData(logger)
+	ctx := r.Context()
+	dataPath, data := loadServedAdrData(ctx)
 
-	exporter, err := adrexport.CreateExporter(logger, "html")
+	exporter, err := adrexport.CreateExporter(ctx, "html")
 	if err != nil {
-		logger.Printf("Error when creating exporter: %v\n", err)
+		utils.Logger(ctx).Warn("Error when creating exporter", "err", err)
 		return
 	}
 
-	exportData := exporter.Export(logger, data, dataPath)
+	exportData, err := exporter.Export(ctx, data, dataPath)
+	if err != nil {
+		utils.Logger(ctx).Warn("Error when creating the html export", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	fmt.Fprint(w, exportData)
 }
 
 func adrHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
 	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) != 3 {
+	combined := serveAllProjects || serveAllLogs
+	if (!combined && len(parts) != 3) || (combined && len(parts) != 4) {
 		w.WriteHeader(404)
 		return
 	}
-	reqIndex, err := strconv.Atoi(parts[2])
+	reqIndex, err := strconv.Atoi(parts[len(parts)-1])
 	if err != nil {
 		w.WriteHeader(404)
 		return
 	}
 
-	adrFile, err := logic.GetAdrFilePathByIndex(reqIndex, logger)
+	var adrFile string
+	if combined {
+		adrFile, err = findAdrPathInOrigin(ctx, parts[2], reqIndex)
+	} else {
+		adrFile, err = logic.GetAdrFilePathByIndex(ctx, reqIndex)
+	}
 	if err != nil {
 		w.WriteHeader(404)
 		return
@@ -94,12 +135,14 @@ func adrHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 type adrInfoForRest struct {
-	Index int
-	Title string
+	Origin         string `json:",omitempty"`
+	Index          int
+	Title          string
+	ReferencedFrom []string `json:",omitempty"`
 }
 
 func adrsHandler(w http.ResponseWriter, r *http.Request) {
-	_, data := loadAdrData(logger)
+	_, data := loadServedAdrData(r.Context())
 
 	parts := strings.Split(r.URL.Path, "/")
 
@@ -128,11 +171,47 @@ func toRestResult(asl []logic.AdrStatus) []adrInfoForRest {
 
 	for _, as := range asl {
 		next := adrInfoForRest{
-			Index: as.Index,
-			Title: as.Title,
+			Origin:         as.Origin,
+			Index:          as.Index,
+			Title:          as.Title,
+			ReferencedFrom: as.ReferencedFrom,
 		}
 		res = append(res, next)
 	}
 
 	return res
 }
+
+// Load the served ADRs; errors are only logged, as the server keeps running.
+func loadServedAdrData(ctx context.Context) (string, []logic.AdrStatus) {
+	logger := utils.Logger(ctx)
+	if serveAllProjects {
+		data, _ := loadAdrDataOfAllProjects(ctx)
+		return "", data
+	}
+	if serveAllLogs {
+		data, _ := loadAdrDataOfAllLogs(ctx)
+		return "", data
+	}
+
+	dataPath, data, _ := loadAdrData(ctx)
+	err := logic.AddReferences(ctx, data)
+	if err != nil {
+		logger.Warn("Error while scanning for ADR references", "err", err)
+	}
+
+	return dataPath, data
+}
+
+// Find the path of an ADR in the combined data of all projects or logs,
+// origin being the name of the project or log.
+func findAdrPathInOrigin(ctx context.Context, origin string, index int) (string, error) {
+	_, data := loadServedAdrData(ctx)
+	for _, as := range data {
+		if as.Origin == origin && as.Index == index {
+			return as.Path, nil
+		}
+	}
+
+	return "", errors.New(fmt.Sprintf("Could not find ADR %d in '%s'", index, origin))
+}
@@ -4,19 +4,20 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	_ "embed"
+	"errors"
 	"fmt"
-	"log"
-	"os"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/dukemarty/adr-go/documents"
+	"github.com/dukemarty/adr-go/utils"
 	"github.com/spf13/cobra"
 )
 
-var availableDocuments = []string{"Changelog", "License"}
-var availableDocumentsForCheck = []string{"Changelog", "License", "CHANGELOG", "LICENSE", "changelog", "license"}
+var availableDocuments = []string{"Changelog", "License", "Output", "Schema"}
+var availableDocumentsForCheck = []string{"Changelog", "License", "Output", "Schema", "CHANGELOG", "LICENSE", "OUTPUT", "SCHEMA", "changelog", "license", "output", "schema"}
 
 // changelogCmd represents the changelog command
 var showCmd = &cobra.Command{
@@ -39,40 +40,55 @@ var showCmd = &cobra.Command{
 	ValidArgs: availableDocumentsForCheck,
 	Args:      cobra.MatchAll(cobra.RangeArgs(0, 1), cobra.OnlyValidArgs),
 
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
 
 		create, _ := cmd.Flags().GetBool("create")
 
 		if create {
-			logger.Println("Command 'show' called with 'create' flag.")
+			logger.Debug("Command 'show' called", "create", create)
+			var written []documentOutput
 			if len(args) == 0 {
-				storeAllDocuments(logger)
+				written = storeAllDocuments(ctx)
 			} else {
-				storeSingleDocument(logger, args[0])
+				written = storeSingleDocument(ctx, args[0])
+			}
+			if structuredOutput(cmd) {
+				return printOutput(cmd, showOutput{Documents: written})
 			}
 		} else {
 			var doc string
 			if len(args) == 0 {
-				logger.Println("Command 'show' called, without a document type selected.")
+				if structuredOutput(cmd) {
+					return &usageError{err: errors.New(fmt.Sprintf("A document must be given with --output %s", outputFormat(cmd)))}
+				}
+				logger.Debug("Command 'show' called")
 				prompt := &survey.Select{
 					Message: "What document shall be shown:",
 					Options: availableDocuments,
 				}
 				survey.AskOne(prompt, &doc)
 			} else {
-				logger.Printf("Command 'show' called, with document type '%s'.\n", args[0])
+				logger.Debug("Command 'show' called", "document", args[0])
 				doc = args[0]
 			}
 
-			switch strings.ToUpper(doc) {
-			case "CHANGELOG":
-				fmt.Println(documents.Changelog)
-			case "LICENSE":
-				fmt.Println(documents.License)
+			df, found := findDocument(doc)
+			if !found {
+				return nil
+			}
+			if structuredOutput(cmd) {
+				return printOutput(cmd, showOutput{Documents: []documentOutput{{Name: df.Name, Content: df.Content}}})
 			}
+			fmt.Println(df.Content)
 		}
+
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: showSchema},
 }
 
 func init() {
@@ -81,32 +97,45 @@ func init() {
 	showCmd.Flags().BoolP("create", "c", false, "create files for all documents")
 }
 
-func storeAllDocuments(logger *log.Logger) {
+// Find an embedded document by its name, ignoring case.
+func findDocument(name string) (documents.DocumentFile, bool) {
 	for _, df := range documents.Docs {
-		err := os.WriteFile(df.Filename, []byte(df.Content), 0644)
-		if err != nil {
-			logger.Printf("Problem writing %s: %v\n", df.Filename, err)
-		} else {
-			logger.Printf("Wrote %s.\n", df.Filename)
+		if strings.EqualFold(df.Name, name) {
+			return df, true
 		}
 	}
+
+	return documents.DocumentFile{}, false
+}
+
+// Store all embedded documents, returns the successfully written ones.
+func storeAllDocuments(ctx context.Context) []documentOutput {
+	res := make([]documentOutput, 0)
+	for _, df := range documents.Docs {
+		res = append(res, storeDocument(ctx, df)...)
+	}
+
+	return res
 }
 
-func storeSingleDocument(logger *log.Logger, docType string) {
-	var filename string
-	var content string
-	switch strings.ToUpper(docType) {
-	case "CHANGELOG":
-		filename = "CHANGELOG.md"
-		content = documents.Changelog
-	case "LICENSE":
-		filename = "LICENSE"
-		content = documents.License
+// Store the selected embedded document, returns it if it was written.
+func storeSingleDocument(ctx context.Context, docType string) []documentOutput {
+	df, found := findDocument(docType)
+	if !found {
+		return make([]documentOutput, 0)
 	}
-	err := os.WriteFile(filename, []byte(content), 0644)
+
+	return storeDocument(ctx, df)
+}
+
+func storeDocument(ctx context.Context, df documents.DocumentFile) []documentOutput {
+	logger := utils.Logger(ctx)
+	err := utils.WriteFile(df.Filename, []byte(df.Content), 0644)
 	if err != nil {
-		logger.Printf("Problem writing %s: %v\n", filename, err)
-	} else {
-		logger.Printf("Wrote %s.\n", filename)
+		logger.Debug("Problem writing document", "file", df.Filename, "err", err)
+		return make([]documentOutput, 0)
 	}
+	logger.Info("Wrote document", "file", df.Filename)
+
+	return []documentOutput{{Name: df.Name, File: df.Filename}}
 }
@@ -5,7 +5,7 @@ package cmd
 
 import (
 	"fmt"
-	"strconv"
+	"path/filepath"
 
 	"github.com/dukemarty/adr-go/data"
 	"github.com/dukemarty/adr-go/logic"
@@ -21,37 +21,72 @@ var statusCmd = &cobra.Command{
 	Long: `Change status of a selected ADR,  may be used interactively.
 
 	The new status can either be provided using the -s/--status flag, or an interactive
-	prompt is shown for the user to select the new status.`,
-	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	prompt is shown for the user to select the new status.
 
-		adrIdx, err := strconv.Atoi(args[0])
-		if err != nil {
-			logger.Fatalf("ERROR: provided ADR index must be number, could not be parsed: %s\n", args[0])
+	If requested with --commit or configured with 'autoCommit' in the project
+	configuration, the changed ADR is committed to git.`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
 		}
+		ctx := cmd.Context()
+
+		adrIdx := args[0]
 
 		var newStatus string
 		// if len(args) > 1 {
 		if len(flagNewStatus) > 1 {
-			logger.Printf("Command 'status' called for ADR #%d with new status %s.\n", adrIdx, args[1])
+			logger.Debug("Command 'status' called", "adr", adrIdx, "status", flagNewStatus.String())
 			// newStatus = args[1]
 			newStatus = flagNewStatus.String()
 		} else {
-			logger.Printf("Command 'status' called for ADR #%d without new status.\n", adrIdx)
-			newStatus = logic.GetStatusInteractively(fmt.Sprintf("ADR #%d()", adrIdx))
+			logger.Debug("Command 'status' called", "adr", adrIdx)
+			newStatus = logic.GetStatusInteractively(fmt.Sprintf("ADR #%s()", adrIdx))
 		}
 
-		adrFile, err := logic.GetAdrFilePathByIndex(adrIdx, logger)
+		if !data.IsSupportedStatus(newStatus) {
+			return &data.InvalidStatusError{Status: newStatus}
+		}
+
+		unlock, err := lockAdrLog(ctx)
 		if err != nil {
+			return err
+		}
+		oldStatus := ""
+		adrFile, err := logic.GetAdrFilePathByIndexString(ctx, adrIdx)
+		if err == nil {
+			var entries []data.StatusChange
+			entries, err = data.ReadStatusEntries(ctx, adrFile)
+			if err == nil && len(entries) > 0 {
+				oldStatus = entries[len(entries)-1].Status
+			}
+		}
+		if err == nil {
+			err = data.AddStatusEntry(ctx, adrFile, newStatus)
+		}
+		unlock()
+		if err != nil {
+			return wrapError(err, "Error changing status of ADR %s", adrIdx)
+		}
 
+		err = commitChanges(cmd, logic.CommitMessageForStatusChange(ctx, adrFile, newStatus), []string{adrFile})
+		if err != nil {
+			return err
 		}
-		data.AddStatusEntry(logger, adrFile, newStatus)
+
+		if structuredOutput(cmd) {
+			return printOutput(cmd, statusOutput{Adr: adrIdx, File: filepath.ToSlash(adrFile), OldStatus: oldStatus, NewStatus: newStatus})
+		}
+
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: statusSchema},
 }
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
 
 	statusCmd.Flags().VarP(&flagNewStatus, "status", "s", fmt.Sprintf(`new status to assign, allowed: %v`, data.SupportedStatus))
+	addCommitFlags(statusCmd)
 }
@@ -0,0 +1,58 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template [<template name>]",
+	Short: "List or pull templates from the central ADR store",
+	Long: `Without argument, list all templates available in the shared folder
+of the central ADR store.
+
+If a template name is provided, that template is copied into the ADR
+folder of the current project, where it can be used e.g. with 'new -t'.
+Templates not found in the ADR folder are looked up in the central store
+anyway, so pulling is only needed to adapt a template for one project.`,
+	Args: cobra.MatchAll(cobra.RangeArgs(0, 1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		if len(args) == 0 {
+			logger.Debug("Command 'template' called")
+
+			templates, err := logic.GetCentralStoreTemplates(ctx)
+			if err != nil {
+				return wrapError(err, "Error reading templates of central ADR store")
+			}
+			for _, t := range templates {
+				fmt.Println(t)
+			}
+			return nil
+		}
+
+		logger.Debug("Command 'template' called", "template", args[0])
+
+		target, err := logic.PullTemplateFromCentralStore(ctx, args[0])
+		if err != nil {
+			return wrapError(err, "Error pulling template '%s'", args[0])
+		}
+		fmt.Printf("Template stored as %s\n", target)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo [count]",
+	Short: "Undo the last operations changing ADRs",
+	Long: `Undo the last operations (default: 1) which changed files of the ADR
+log, e.g. new, status, update or retitle: the files changed by each operation
+are restored to their previous state, files created by it are removed.
+
+All operations are recorded in a journal in the folder '.adr' of the project
+(which is ignored by git); the last 50 operations are kept. With --list, the
+recorded operations are shown instead, the most recent one first.
+
+Changes committed to git are not undone in git; the restored files show up
+as uncommitted changes.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		list, _ := cmd.Flags().GetBool("list")
+
+		count := 1
+		if len(args) > 0 {
+			var err error
+			count, err = strconv.Atoi(args[0])
+			if err != nil || count < 1 {
+				return &usageError{err: errors.New(fmt.Sprintf("Invalid number of operations '%s', must be a positive number", args[0]))}
+			}
+		}
+
+		logger.Debug("Command 'undo' called", "count", count, "list", list)
+
+		if list {
+			entries, err := logic.GetJournalOperations(ctx)
+			if err != nil {
+				return wrapError(err, "Error reading journal")
+			}
+			for _, e := range entries {
+				fmt.Printf("%s  %s (%d files)\n", e.Time.Format("2006-01-02 15:04:05"), e.Operation, len(e.Files))
+			}
+			return nil
+		}
+
+		undone, err := logic.UndoOperations(ctx, count)
+		for _, e := range undone {
+			fmt.Printf("Undone: %s\n", e.Operation)
+		}
+		if err != nil {
+			return err
+		}
+		if len(undone) == 0 {
+			fmt.Println("Nothing to undo.")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().BoolP("list", "l", false, "list the recorded operations instead of undoing")
+}
@@ -4,7 +4,10 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -12,23 +15,70 @@ import (
 var updateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update ADR",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Long: `Update the ADR repository: ADR files whose names do not fit to their
+index and title (e.g. after the title was changed) are renamed, links to
+ADRs in other ADR logs are resolved, and the TOC is regenerated.
+
+Links to renamed ADRs are rewritten in all ADRs, and in the markdown files
+of the folders configured with 'docFolders' in the project configuration.
+
+With --verbose-diff, the content changes are printed as unified diff (as
+done by the global --dry-run, which only prints the changes).
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changes are committed to git.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		verboseDiff, _ := cmd.Flags().GetBool("verbose-diff")
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+		logger.Debug("Command 'update' called", "verboseDiff", verboseDiff)
 
-		logger.Println("Command 'update' called.")
+		result, err := logic.UpdateAdrRepository(ctx)
+		if err != nil {
+			return wrapError(err, "Error updating ADR repository")
+		}
 
-		logic.UpdateAdrRepository(logger)
+		if structuredOutput(cmd) {
+			if err := printOutput(cmd, toUpdateOutput(result)); err != nil {
+				return err
+			}
+		} else {
+			printUpdateResult(result, verboseDiff)
+		}
 
-		logger.Println("Filenames updated as required.")
+		logger.Debug("Filenames updated as required")
+
+		files := result.Changed
+		for _, r := range result.Renames {
+			files = append(files, r.From)
+		}
+		return commitChanges(cmd, logic.CommitMessageForUpdate(result), files)
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: updateSchema},
+}
+
+// Print the renames and changed files of an update, with verboseDiff each
+// content change as unified diff (unless in a dry run, which prints them
+// anyway).
+func printUpdateResult(result logic.UpdateResult, verboseDiff bool) {
+	for _, r := range result.Renames {
+		fmt.Printf("Rename %s -> %s\n", r.From, r.To)
+	}
+	for _, c := range result.Changes {
+		fmt.Printf("Update %s\n", c.Path)
+		if verboseDiff && !utils.IsDryRun() {
+			fmt.Print(utils.UnifiedDiff("a/"+c.Path, "b/"+c.Path, c.Before, c.After))
+		}
+	}
 }
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
+
+	updateCmd.Flags().BoolP("verbose-diff", "d", false, "print the content changes as unified diff")
+	addCommitFlags(updateCmd)
 }
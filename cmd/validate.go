@@ -0,0 +1,86 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the ADR repository",
+	Long: `Validate the ADRs and the TOC of the repository:
+
+  - the filename must fit to the number and title in the ADR's heading
+  - all entries of the 'Status' section must be of the form
+    '<YYYY-MM-DD> <status>', with a known status
+  - the TOC (README.md) must be up-to-date
+
+Each problem is printed with file and line, and the exit code is 1 if there
+are any. With --staged, only the ADRs staged in git are validated (as done
+by the pre-commit hook, see 'hooks install'). With --fix, filenames and TOC
+are fixed before (like 'update' does), and the fixed files are staged.`,
+	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		staged, _ := cmd.Flags().GetBool("staged")
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		logger.Debug("Command 'validate' called", "staged", staged, "fix", fix)
+
+		res := validateOutput{Fixed: make([]renameOutput, 0), Issues: make([]issueOutput, 0)}
+		if fix {
+			result, err := logic.FixAndStageAdrRepository(ctx)
+			if err != nil {
+				return wrapError(err, "Error fixing ADR repository")
+			}
+			for _, r := range result.Renames {
+				res.Fixed = append(res.Fixed, renameOutput{From: filepath.ToSlash(r.From), To: filepath.ToSlash(r.To)})
+				if !structuredOutput(cmd) {
+					fmt.Printf("Fixed filename %s -> %s\n", r.From, r.To)
+				}
+			}
+		}
+
+		issues, err := logic.ValidateAdrRepository(ctx, staged)
+		if err != nil {
+			return wrapError(err, "Error validating ADR repository")
+		}
+		if structuredOutput(cmd) {
+			for _, issue := range issues {
+				res.Issues = append(res.Issues, issueOutput{File: filepath.ToSlash(issue.File), Line: issue.Line, Message: issue.Message})
+			}
+			if err := printOutput(cmd, res); err != nil {
+				return err
+			}
+		} else {
+			for _, issue := range issues {
+				fmt.Fprintln(os.Stderr, issue.String())
+			}
+		}
+		if len(issues) > 0 {
+			return &checkFailedError{message: fmt.Sprintf("ADR validation failed with %d problem(s).", len(issues))}
+		}
+
+		return nil
+	},
+	Annotations: map[string]string{outputSchemaAnnotation: validateSchema},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().BoolP("staged", "s", false, "only validate the ADRs staged in git")
+	validateCmd.Flags().BoolP("fix", "f", false, "fix filenames and TOC before validating, and stage the fixes in git")
+}
@@ -19,22 +19,35 @@ var versionCmd = &cobra.Command{
 	Long: `Output information about the program version:
 	version number and, if available, git revision.`,
 	Args: cobra.MatchAll(cobra.NoArgs, cobra.OnlyValidArgs),
-	Run: func(cmd *cobra.Command, args []string) {
-		initCommon(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
 
-		logger.Println("Command 'version' called.")
+		logger.Debug("Command 'version' called")
 
-		revisionInfo := ""
+		revision := ""
 		if bi, ok := debug.ReadBuildInfo(); ok {
 			for _, setting := range bi.Settings {
 				if setting.Key == "vcs.revision" {
-					revisionInfo = fmt.Sprintf(" (git revision: %s)", setting.Value)
+					revision = setting.Value
 				}
 			}
 		}
 
+		if structuredOutput(cmd) {
+			return printOutput(cmd, versionOutput{Version: VERSION, Revision: revision})
+		}
+
+		revisionInfo := ""
+		if len(revision) > 0 {
+			revisionInfo = fmt.Sprintf(" (git revision: %s)", revision)
+		}
 		fmt.Printf("%s%s\n", VERSION, revisionInfo)
+
+		return nil
 	},
+	Annotations: map[string]string{outputSchemaAnnotation: versionSchema},
 }
 
 func init() {
@@ -0,0 +1,50 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/spf13/cobra"
+)
+
+// withdrawCmd represents the withdraw command
+var withdrawCmd = &cobra.Command{
+	Use:   "withdraw <adr index>",
+	Short: "Withdraw one ADR",
+	Long: `Withdraw the selected ADR, e.g. a proposal which was dropped: a status
+entry 'Withdrawn' is added (with the note given by --note), and the ADR is
+left out of the TOC. The file itself is kept.
+
+If requested with --commit or configured with 'autoCommit' in the project
+configuration, the changes are committed to git.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := initCommon(cmd); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+
+		note, _ := cmd.Flags().GetString("note")
+
+		logger.Debug("Command 'withdraw' called", "adr", args[0], "note", note)
+
+		result, err := logic.WithdrawAdr(ctx, args[0], note)
+		if err != nil {
+			return wrapError(err, "Error withdrawing ADR %s", args[0])
+		}
+
+		printUpdateResult(result, false)
+
+		return commitChanges(cmd, fmt.Sprintf("docs(adr): withdraw ADR %s", args[0]), result.Changed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(withdrawCmd)
+
+	withdrawCmd.Flags().StringP("note", "m", "", "note added to the status entry, e.g. the reason")
+	addCommitFlags(withdrawCmd)
+}
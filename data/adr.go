@@ -4,11 +4,11 @@
 package data
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
-	"path"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -24,60 +24,131 @@ type AdrVars struct {
 
 type AdrInfo struct {
 	RelativePath string
-	Index        int
-	Title        string
+	// Category of the ADR, i.e. the subfolder of the ADR folder containing
+	// it; empty for ADRs directly in the ADR folder.
+	Category string
+	// Number of the ADR as written in its heading, including a prefix, e.g. "SEC-001".
+	Id    string
+	Index int
+	Title string
+	// Set if the ADR is in the archive folder.
+	Archived bool
 }
 
-func LoadAdrInfo(logger *log.Logger, basepath string, adrFile string) (AdrInfo, error) {
-	var res AdrInfo
-	res.RelativePath = filepath.Join(basepath, adrFile)
+// Subfolder of the ADR folder containing the archived ADRs, which keep
+// their category (as subfolder of the archive folder).
+const ArchiveFolder = "archive"
 
-	index, title, err := extractAdrBaseInfoFromFile(logger, res.RelativePath)
+// Regex for the number part of an ADR heading: optional prefix, digits, and a dot.
+var headingIdRegex = regexp.MustCompile(`^(.*?)(\d+)\.?$`)
+
+func LoadAdrInfo(ctx context.Context, basepath string, adrFile string) (AdrInfo, error) {
+	res := newAdrInfo(basepath, adrFile)
+	doc, err := utils.OpenMarkdownFile(res.RelativePath)
 	if err != nil {
 		return res, err
 	}
-	res.Index = index
-	res.Title = title
+	err = res.readHeading(ctx, doc)
 
-	return res, nil
+	return res, err
 }
 
-func extractAdrBaseInfoFromFile(logger *log.Logger, adrFile string) (int, string, error) {
-	doc, err := utils.OpenMarkdownFile(adrFile)
+// Get the info of an ADR from the given content instead of its file, e.g.
+// from the content staged in git.
+func ParseAdrInfo(ctx context.Context, basepath string, adrFile string, content []byte) (AdrInfo, error) {
+	res := newAdrInfo(basepath, adrFile)
+	doc, err := utils.ParseMarkdown(content)
+	if err != nil {
+		return res, err
+	}
+	err = res.readHeading(ctx, doc)
+
+	return res, err
+}
+
+func newAdrInfo(basepath string, adrFile string) AdrInfo {
+	return AdrInfo{RelativePath: filepath.Join(basepath, adrFile), Category: CategoryOfAdrFile(adrFile), Archived: IsArchivedAdrFile(adrFile)}
+}
+
+// Fill id, index and title from the heading of the ADR.
+func (info *AdrInfo) readHeading(ctx context.Context, doc *utils.MarkdownDoc) error {
+	id, index, title, err := extractAdrBaseInfo(ctx, info.RelativePath, doc)
+	if err != nil {
+		return err
+	}
+	info.Id = id
+	info.Index = index
+	info.Title = title
 
+	return nil
+}
+
+// Check if an ADR is archived, from its filename relative to the ADR folder.
+func IsArchivedAdrFile(adrFile string) bool {
+	return strings.HasPrefix(filepath.ToSlash(adrFile), ArchiveFolder+"/")
+}
+
+// Get the category of an ADR from its filename relative to the ADR folder.
+// For archived ADRs, the category they had before archiving is returned.
+func CategoryOfAdrFile(adrFile string) string {
+	if IsArchivedAdrFile(adrFile) {
+		adrFile = filepath.ToSlash(adrFile)[len(ArchiveFolder)+1:]
+	}
+	dir := filepath.Dir(adrFile)
+	if dir == "." {
+		return ""
+	}
+
+	return filepath.ToSlash(dir)
+}
+
+func extractAdrBaseInfo(ctx context.Context, adrFile string, doc *utils.MarkdownDoc) (string, int, string, error) {
+	logger := utils.Logger(ctx)
 	node := doc.Doc.FirstChild()
 	for node != nil && !(node.Kind().String() == "Heading") {
 		node = node.NextSibling()
 	}
-	logger.Printf("Extracted heading line: %s\n", string(node.Text(doc.Source)))
+	if node == nil {
+		return "", -1, "", &ParseError{File: adrFile, Reason: "no heading found"}
+	}
+	logger.Debug("Extracted heading line", "heading", string(node.Text(doc.Source)))
 
 	// TODO: only very basic, error-prone parsing used here, improve!
 	tokens := strings.Split(string(node.Text(doc.Source)), " ")
 
-	indexPart := tokens[0][:len(tokens[0])-1]
-	index, err := strconv.Atoi(indexPart)
+	match := headingIdRegex.FindStringSubmatch(tokens[0])
+	if match == nil {
+		logger.Debug("Could not parse ADR index", "index", tokens[0])
+		return "", -1, "", &ParseError{File: adrFile, Reason: fmt.Sprintf("could not parse '%s' as index", tokens[0])}
+	}
+	index, err := strconv.Atoi(match[2])
 	if err != nil {
-		logger.Printf("Could not parse '%s' as index: %v\n", indexPart, err)
-		return -1, "", err
+		logger.Debug("Could not parse ADR index", "index", match[2], "err", err)
+		return "", -1, "", &ParseError{File: adrFile, Reason: fmt.Sprintf("could not parse '%s' as index", match[2])}
 	}
 	title := strings.Join(tokens[1:], " ")
 
-	return index, title, nil
+	return strings.TrimSuffix(tokens[0], "."), index, title, nil
 }
 
 type StatusChange struct {
-	Date   string
-	Status string
+	Date   string `json:"date" yaml:"date"`
+	Status string `json:"status" yaml:"status"`
 }
 
 // ReadStatusEntries can be used to single out and read the status section of an ADR.
-func ReadStatusEntries(logger *log.Logger, adrFile string) ([]StatusChange, error) {
+func ReadStatusEntries(ctx context.Context, adrFile string) ([]StatusChange, error) {
+	logger := utils.Logger(ctx)
 	doc, err := utils.OpenMarkdownFile(adrFile)
 	if err != nil {
-		logger.Fatalf("Could not read data from ADR '%s': %v", adrFile, err)
+		logger.Debug("Could not read data from ADR", "file", adrFile, "err", err)
+		return nil, err
 	}
 
 	section := doc.FindMarkdownSection("Status")
+	if section == nil {
+		return nil, &ParseError{File: adrFile, Reason: "no 'Status' section found"}
+	}
 
 	res := make([]StatusChange, 0)
 	nextChild := section.NextSibling()
@@ -85,7 +156,7 @@ func ReadStatusEntries(logger *log.Logger, adrFile string) ([]StatusChange, erro
 		line := string(nextChild.Text(doc.Source))
 		tokens := strings.Split(line, " ")
 		if len(tokens) < 2 {
-			logger.Printf("Status line '%s' does not contain all required information!", line)
+			logger.Debug("Status line does not contain all required information", "line", line)
 			nextChild = nextChild.NextSibling()
 			continue
 		}
@@ -96,21 +167,79 @@ func ReadStatusEntries(logger *log.Logger, adrFile string) ([]StatusChange, erro
 	return res, nil
 }
 
-func AddStatusEntry(logger *log.Logger, adrFile string, newStatus string) {
-	doc, err := utils.OpenMarkdownFile(adrFile)
+// Add a status entry with the new status and the current date at the end
+// of the status section of the ADR.
+func AddStatusEntry(ctx context.Context, adrFile string, newStatus string) error {
+	logger := utils.Logger(ctx)
+	if !IsSupportedStatus(newStatus) {
+		return &InvalidStatusError{Status: newStatus}
+	}
+	content, err := utils.ReadFile(adrFile)
 	if err != nil {
-		logger.Fatalf("Could not read data from ADR '%s': %v", adrFile, err)
+		logger.Debug("Could not read data from ADR", "file", adrFile, "err", err)
+		return err
 	}
 
-	prePart, postPart := doc.FindInsertAtEndOfSection("Status")
+	updated, err := WithStatusEntry(string(content), newStatus, "")
+	if err != nil {
+		return &ParseError{File: adrFile, Reason: err.Error()}
+	}
 
-	err = os.Rename(adrFile, path.Join(adrFile+".bak"))
+	err = utils.WriteFile(adrFile, []byte(updated), 0644)
 	if err != nil {
-		logger.Printf("Could not rename ADR file '%s': %v\n", adrFile, err)
+		logger.Warn("Could not write changed ADR file", "file", adrFile, "err", err)
 	}
 
-	err = os.WriteFile(adrFile, []byte(fmt.Sprintf("%s\n%v %s\n%s", prePart, time.Now().Format("2006-01-02"), newStatus, postPart)), 0644)
+	return err
+}
+
+// Replace the heading line of an ADR, i.e. its first level-1
+// heading, by '# <id>. <title>'.
+func SetAdrHeading(ctx context.Context, adrFile string, id string, title string) error {
+	logger := utils.Logger(ctx)
+	content, err := utils.ReadFile(adrFile)
 	if err != nil {
-		logger.Printf("Could not write changed ADR file '%s': %v\n", adrFile, err)
+		return errors.New(fmt.Sprintf("Could not read data from ADR '%s': %v", adrFile, err))
 	}
+
+	updated, err := WithAdrHeading(string(content), id, title)
+	if err != nil {
+		return &ParseError{File: adrFile, Reason: "no heading found"}
+	}
+	logger.Debug("Replacing heading", "id", id, "title", title, "file", adrFile)
+
+	return utils.WriteFile(adrFile, []byte(updated), 0644)
+}
+
+// Get the ADR content with its heading line replaced by '# <id>. <title>'.
+func WithAdrHeading(content string, id string, title string) (string, error) {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# ") {
+			lines[i] = fmt.Sprintf("# %s. %s", id, title)
+			return strings.Join(lines, "\n"), nil
+		}
+	}
+
+	return content, errors.New("No heading found")
+}
+
+// Get the ADR content with a new entry '<date> <status> <note>' at the end
+// of its status section; the note may be empty.
+func WithStatusEntry(content string, status string, note string) (string, error) {
+	doc, err := utils.ParseMarkdown([]byte(content))
+	if err != nil {
+		return content, err
+	}
+	if doc.FindMarkdownSection("Status") == nil {
+		return content, errors.New("No 'Status' section found")
+	}
+
+	entry := fmt.Sprintf("%v %s", time.Now().Format("2006-01-02"), status)
+	if len(note) > 0 {
+		entry += " " + note
+	}
+	prePart, postPart := doc.FindInsertAtEndOfSection("Status")
+
+	return fmt.Sprintf("%s\n%s\n%s", prePart, entry, postPart), nil
 }
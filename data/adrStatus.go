@@ -1,8 +1,6 @@
 package data
 
 import (
-	"errors"
-	"fmt"
 	"strings"
 
 	"golang.org/x/exp/slices"
@@ -10,8 +8,11 @@ import (
 	"golang.org/x/text/language"
 )
 
-var SupportedStatus = []string{"Proposed", "Accepted", "Done", "Deprecated", "Superseded"}
-var statusForComparisons = []string{"PROPOSED", "ACCEPTED", "DONE", "DEPRECATED", "SUPERSEDED"}
+var SupportedStatus = []string{"Proposed", "Accepted", "Done", "Deprecated", "Superseded", "Withdrawn"}
+var statusForComparisons = []string{"PROPOSED", "ACCEPTED", "DONE", "DEPRECATED", "SUPERSEDED", "WITHDRAWN"}
+
+// Status of ADRs which were withdrawn, see the withdraw command.
+const WithdrawnStatus = "Withdrawn"
 
 type AdrStatus string
 
@@ -20,18 +21,25 @@ func (e *AdrStatus) String() string {
 	return string(*e)
 }
 
-// Set must have pointer receiver so it doesn't change the value of a copy
+// Set must have pointer receiver so it doesn't change the value of a copy.
+// Unsupported values are kept as they are, to be reported by the command
+// as InvalidStatusError (see IsSupportedStatus).
 func (e *AdrStatus) Set(v string) error {
 	if slices.Contains(statusForComparisons, strings.ToUpper(v)) {
 		caser := cases.Title(language.English)
 		*e = AdrStatus(caser.String(v))
-		return nil
 	} else {
-		return errors.New(fmt.Sprintf(`must be one of: %v`, SupportedStatus))
+		*e = AdrStatus(v)
 	}
+	return nil
 }
 
 // Type is only used in help text
 func (e *AdrStatus) Type() string {
 	return "AdrStatus"
 }
+
+// Check if status is one of the supported status (case-insensitive).
+func IsSupportedStatus(status string) bool {
+	return slices.Contains(statusForComparisons, strings.ToUpper(status))
+}
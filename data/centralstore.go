@@ -0,0 +1,90 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+const CentralStoreRegistryFilename = "projects.json"
+const CentralStoreTemplatesFolder = "templates"
+
+// {"projects":[{"name":"billing","path":"/home/me/src/billing"}]}
+
+type RegisteredProject struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// A CentralStore is a folder shared between several ADR projects. It contains
+// the registry of all known projects, and a folder with shared templates.
+type CentralStore struct {
+	Path     string              `json:"-"`
+	Projects []RegisteredProject `json:"projects"`
+}
+
+// LoadCentralStore reads the registry of the central ADR store located at
+// storePath. A store without registry file is treated as empty store.
+func LoadCentralStore(storePath string) (CentralStore, error) {
+	store := CentralStore{Path: storePath, Projects: make([]RegisteredProject, 0)}
+
+	if len(storePath) == 0 {
+		return store, errors.New("No central ADR store configured, use 'config --store' to set one.")
+	}
+
+	content, err := os.ReadFile(filepath.Join(storePath, CentralStoreRegistryFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return store, err
+	}
+	err = json.Unmarshal(content, &store)
+	if err != nil {
+		return store, err
+	}
+
+	return store, nil
+}
+
+// LoadConfiguredCentralStore loads the central ADR store which is configured
+// in the user configuration.
+func LoadConfiguredCentralStore() (CentralStore, error) {
+	config, err := LoadUserConfiguration()
+	if err != nil {
+		return CentralStore{}, errors.New(fmt.Sprintf("Could not load user configuration: %v", err))
+	}
+
+	return LoadCentralStore(config.CentralAdrStore)
+}
+
+// Register adds a project to the store, or updates the path of an already
+// registered project with the same name.
+func (store *CentralStore) Register(name string, projectPath string) {
+	for i, p := range store.Projects {
+		if p.Name == name {
+			store.Projects[i].Path = projectPath
+			return
+		}
+	}
+
+	store.Projects = append(store.Projects, RegisteredProject{Name: name, Path: projectPath})
+}
+
+func (store CentralStore) TemplatesPath() string {
+	return filepath.Join(store.Path, CentralStoreTemplatesFolder)
+}
+
+func (store CentralStore) Store() error {
+	if err := utils.MkdirAll(store.TemplatesPath()); err != nil {
+		return err
+	}
+
+	content, _ := json.MarshalIndent(store, "", "    ")
+
+	return utils.WriteFile(filepath.Join(store.Path, CentralStoreRegistryFilename), content, 0644)
+}
@@ -2,17 +2,72 @@ package data
 
 import (
 	"encoding/json"
-	"os"
+	"path/filepath"
+
+	"github.com/dukemarty/adr-go/utils"
 )
 
+const ConfigFilename = ".adr.json"
+
 // {"language":"en","path":"docs/adr/","prefix":"abc","digits":3}
 
 type Configuration struct {
-	Language     string `json:"language"`
-	Path         string `json:"path"`
-	Prefix       string `json:"prefix"`
-	Digits       int    `json:"digits"`
-	TemplateName string `json:"template"`
+	// Name of the ADR log, used to select it in a repository with several
+	// ADR logs; if empty, the name of the directory containing the config
+	// file is used.
+	Name         string `json:"name,omitempty" yaml:"name,omitempty"`
+	Language     string `json:"language" yaml:"language"`
+	Path         string `json:"path" yaml:"path"`
+	Prefix       string `json:"prefix" yaml:"prefix"`
+	Digits       int    `json:"digits" yaml:"digits"`
+	TemplateName string `json:"template" yaml:"template"`
+	// Configuration of categories (subfolders of the ADR folder) which use a
+	// number sequence of their own; all other categories share the global one.
+	Categories map[string]CategoryConfiguration `json:"categories,omitempty" yaml:"categories,omitempty"`
+	// Commit changed ADR files to git after each operation.
+	AutoCommit bool `json:"autoCommit,omitempty" yaml:"autoCommit,omitempty"`
+	// Create a branch for each new (proposed) ADR before committing it.
+	ProposalBranches bool `json:"proposalBranches,omitempty" yaml:"proposalBranches,omitempty"`
+	// Regex finding the ADRs a commit implements in its message; the first
+	// group contains one or several (comma-separated) ADR ids. If empty,
+	// trailers like 'ADR: 0012' are used.
+	CommitPattern string `json:"commitPattern,omitempty" yaml:"commitPattern,omitempty"`
+	// Regex for references to ADRs in source code, the first group matching
+	// the ADR id; if empty, references like 'ADR-0007' or 'ADR 7' are found.
+	ReferencePattern string `json:"referencePattern,omitempty" yaml:"referencePattern,omitempty"`
+	// Globs of the source files to scan for references to ADRs (all files if
+	// empty), and of source files not to scan.
+	ReferenceInclude []string `json:"referenceInclude,omitempty" yaml:"referenceInclude,omitempty"`
+	ReferenceExclude []string `json:"referenceExclude,omitempty" yaml:"referenceExclude,omitempty"`
+	// Additional folders (relative to the project directory) with markdown
+	// documents whose links to ADRs are updated when ADRs are renamed.
+	DocFolders []string `json:"docFolders,omitempty" yaml:"docFolders,omitempty"`
+	// Folder (relative to the project directory) with templates ('*.tmpl')
+	// of additional export formats; if empty, the subfolder 'exporters' of
+	// the ADR folder is used.
+	ExporterTemplates string `json:"exporterTemplates,omitempty" yaml:"exporterTemplates,omitempty"`
+}
+
+// {"prefix":"SEC-","digits":3}
+
+type CategoryConfiguration struct {
+	Prefix string `json:"prefix" yaml:"prefix"`
+	Digits int    `json:"digits" yaml:"digits"`
+}
+
+// Get the numbering used for ADRs of the given category: the category's own
+// prefix and digits if it has its own number sequence, otherwise the global
+// ones. The last return value tells if the category has its own sequence.
+func (config Configuration) NumberingOfCategory(category string) (string, int, bool) {
+	cc, ok := config.Categories[category]
+	if !ok || len(category) == 0 {
+		return config.Prefix, config.Digits, false
+	}
+	if cc.Digits <= 0 {
+		cc.Digits = config.Digits
+	}
+
+	return cc.Prefix, cc.Digits, true
 }
 
 func NewConfiguration(lang string, path string, prefix string, digits int, template string) *Configuration {
@@ -28,9 +83,15 @@ func NewConfiguration(lang string, path string, prefix string, digits int, templ
 }
 
 func LoadConfiguration() (Configuration, error) {
+	return LoadConfigurationFrom("")
+}
+
+// LoadConfigurationFrom reads the project configuration file located in the
+// directory dir, which is the root directory of an ADR project.
+func LoadConfigurationFrom(dir string) (Configuration, error) {
 	var config Configuration
 
-	content, err := os.ReadFile(".adr.json")
+	content, err := utils.ReadFile(filepath.Join(dir, ConfigFilename))
 	if err != nil {
 		return config, err
 	}
@@ -45,7 +106,7 @@ func LoadConfiguration() (Configuration, error) {
 func (config Configuration) Store(filepath string) error {
 	content, _ := json.MarshalIndent(config, "", " ")
 
-	errorRes := os.WriteFile(filepath, content, 0644)
+	errorRes := utils.WriteFile(filepath, content, 0644)
 
 	return errorRes
 }
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"fmt"
+)
+
+// Error for a directory without an initialized ADR log, i.e. without
+// (readable) configuration file.
+type NotInitializedError struct {
+	Dir string
+	// Error loading the configuration file.
+	Err error
+}
+
+func (e *NotInitializedError) Error() string {
+	dir := e.Dir
+	if len(dir) == 0 {
+		dir = "."
+	}
+	return fmt.Sprintf("No ADR log initialized in '%s' (see command 'init'): %v", dir, e.Err)
+}
+
+func (e *NotInitializedError) Unwrap() error {
+	return e.Err
+}
+
+// Error for an ADR which could not be found by its index or id.
+type AdrNotFoundError struct {
+	Id string
+}
+
+func (e *AdrNotFoundError) Error() string {
+	return fmt.Sprintf("Could not find ADR %s", e.Id)
+}
+
+// Error for an ADR file which could not be read or parsed.
+type ParseError struct {
+	File   string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Could not parse ADR '%s': %s", e.File, e.Reason)
+}
+
+// Error for a status which is not one of SupportedStatus.
+type InvalidStatusError struct {
+	Status string
+}
+
+func (e *InvalidStatusError) Error() string {
+	return fmt.Sprintf("Invalid status '%s', must be one of: %v", e.Status, SupportedStatus)
+}
+
+// Error for a search keyword which is no valid regular expression.
+type InvalidKeywordError struct {
+	Keyword string
+	// Error compiling the keyword.
+	Err error
+}
+
+func (e *InvalidKeywordError) Error() string {
+	return fmt.Sprintf("Invalid search keyword '%s': %v", e.Keyword, e.Err)
+}
+
+func (e *InvalidKeywordError) Unwrap() error {
+	return e.Err
+}
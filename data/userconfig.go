@@ -1,10 +1,12 @@
 package data
 
 import (
+	"context"
 	"encoding/json"
-	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/dukemarty/adr-go/utils"
 )
 
 const UserConfigFilename = ".adr-go"
@@ -25,14 +27,23 @@ func NewUserConfiguration(editor string, store string) *UserConfiguration {
 	return &uc
 }
 
+// Get the path of the user configuration file in the home directory.
+func UserConfigurationPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, UserConfigFilename), nil
+}
+
 func LoadUserConfiguration() (UserConfiguration, error) {
 	var config UserConfiguration
 
-	home, err := os.UserHomeDir()
+	configPath, err := UserConfigurationPath()
 	if err != nil {
 		return config, err
 	}
-	configPath := filepath.Join(home, UserConfigFilename)
 	content, err := os.ReadFile(configPath)
 	if err != nil {
 		return config, err
@@ -46,23 +57,23 @@ func LoadUserConfiguration() (UserConfiguration, error) {
 }
 
 func (config UserConfiguration) Store() error {
-	home, err := os.UserHomeDir()
+	configPath, err := UserConfigurationPath()
 	if err != nil {
 		return err
 	}
-	configPath := filepath.Join(home, UserConfigFilename)
 
 	content, _ := json.MarshalIndent(config, "", "    ")
 
-	errorRes := os.WriteFile(configPath, content, 0644)
+	errorRes := utils.WriteFile(configPath, content, 0644)
 
 	return errorRes
 }
 
-func LoadEditor(logger *log.Logger) string {
+func LoadEditor(ctx context.Context) string {
+	logger := utils.Logger(ctx)
 	config, err := LoadUserConfiguration()
 	if err != nil {
-		logger.Printf("Error loading user configuration: %v\n", err)
+		logger.Debug("Error loading user configuration", "err", err)
 		return ""
 	}
 
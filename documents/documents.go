@@ -10,12 +10,21 @@ var Changelog string
 //go:embed LICENSE
 var License string
 
+//go:embed OUTPUT.md
+var Output string
+
+//go:embed adrs.schema.json
+var Schema string
+
 type DocumentFile struct {
+	Name     string
 	Filename string
 	Content  string
 }
 
 var Docs = []DocumentFile{
-	{Filename: "CHANGELOG.md", Content: Changelog},
-	{Filename: "LICENSE", Content: License},
+	{Name: "Changelog", Filename: "CHANGELOG.md", Content: Changelog},
+	{Name: "License", Filename: "LICENSE", Content: License},
+	{Name: "Output", Filename: "OUTPUT.md", Content: Output},
+	{Name: "Schema", Filename: "adrs.schema.json", Content: Schema},
 }
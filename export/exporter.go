@@ -2,54 +2,95 @@ package adrexport
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"html/template"
-	"log"
 	"os"
-	"path"
 	"sort"
 	"strings"
 
 	_ "embed"
 
 	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/text"
 	"go.abhg.dev/goldmark/toc"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
 )
 
-// Interface for exporters, transforming a list of ADRs (status infos) into a string.
+// Interface for exporters, transforming a list of ADRs (status infos) into a
+// string; an error is returned if the export could not be created.
 type AdrListExporter interface {
-	Export(logger *log.Logger, data []logic.AdrStatus, dataPath string) string
+	Export(ctx context.Context, data []logic.AdrStatus, dataPath string) (string, error)
 }
 
-// List of supported exporter types.
-var SupportedExporters = []string{"csv", "json", "markdown", "html"}
+// Function creating a new exporter.
+type ExporterFactory func() AdrListExporter
+
+// Registered exporters by their (lower case) type, and the types in the
+// order of their registration.
+var exporterRegistry = make(map[string]ExporterFactory)
+var exporterTypes = make([]string, 0)
+
+func init() {
+	RegisterExporter("csv", func() AdrListExporter { return CsvExporter{} })
+	RegisterExporter("json", func() AdrListExporter { return JsonExporter{} })
+	RegisterExporter("json-full", func() AdrListExporter { return JsonFullExporter{} })
+	RegisterExporter("markdown", func() AdrListExporter { return MarkdownExporter{} })
+	RegisterExporter("html", func() AdrListExporter { return HtmlExporter{} })
+	RegisterExporter("yaml", func() AdrListExporter { return YamlExporter{} })
+	RegisterExporter("ndjson", func() AdrListExporter { return NdjsonExporter{} })
+	RegisterExporter("xml", func() AdrListExporter { return XmlExporter{} })
+}
+
+// Register the exporter created by factory for the type expType, which
+// makes it available to CreateExporter and the export command. A
+// previously registered exporter of the same type is replaced.
+func RegisterExporter(expType string, factory ExporterFactory) {
+	expType = strings.ToLower(expType)
+	if _, ok := exporterRegistry[expType]; !ok {
+		exporterTypes = append(exporterTypes, expType)
+	}
+	exporterRegistry[expType] = factory
+}
+
+// File extensions of the exporters' output, if different from their type.
+var exporterFileExtensions = map[string]string{"markdown": "md", "json-full": "json"}
+
+// Get the file extension (without dot) for the output of the exporter type.
+func ExporterFileExtension(expType string) string {
+	expType = strings.ToLower(expType)
+	if ext, ok := exporterFileExtensions[expType]; ok {
+		return ext
+	}
 
-func CreateExporter(logger *log.Logger, expType string) (AdrListExporter, error) {
-	var res AdrListExporter = nil
-	var resErr error = nil
-	switch strings.ToLower(expType) {
-	case "csv":
-		res = CsvExporter{}
-	case "json":
-		res = JsonExporter{}
-	case "markdown":
-		res = MarkdownExporter{}
-	case "html":
-		res = HtmlExporter{}
-	default:
-		logger.Printf("Exporter type '%s' not supported!\n", expType)
-		resErr = errors.New(fmt.Sprintf("Exporter type '%s' not supported!", expType))
+	return expType
+}
+
+// Get the list of supported exporter types, in the order of their
+// registration.
+func SupportedExporters() []string {
+	return slices.Clone(exporterTypes)
+}
+
+func CreateExporter(ctx context.Context, expType string) (AdrListExporter, error) {
+	logger := utils.Logger(ctx)
+	factory, ok := exporterRegistry[strings.ToLower(expType)]
+	if !ok {
+		logger.Debug("Exporter type not supported", "format", expType)
+		return nil, errors.New(fmt.Sprintf("Exporter type '%s' not supported!", expType))
 	}
 
-	return res, resErr
+	return factory(), nil
 }
 
 // ----------------------------------------------------------------------------
@@ -58,28 +99,43 @@ func CreateExporter(logger *log.Logger, expType string) (AdrListExporter, error)
 // Empty struct to represent an exporter of csv data.
 type CsvExporter struct{}
 
-func (CsvExporter) Export(logger *log.Logger, entries []logic.AdrStatus, _ string) string {
+func (CsvExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
+	logger := utils.Logger(ctx)
 	buf := new(bytes.Buffer)
 	w := csv.NewWriter(buf)
 
-	w.Write([]string{"Index", "Decision", "Last Modified Date", "Last Status"})
+	// entries of a combined export get an additional column for their origin
+	withOrigin := false
+	for _, e := range entries {
+		withOrigin = withOrigin || len(e.Origin) > 0
+	}
+
+	header := []string{"Index", "Decision", "Last Modified Date", "Last Status"}
+	if withOrigin {
+		header = append([]string{"Origin"}, header...)
+	}
+	w.Write(header)
 	if err := w.Error(); err != nil {
-		logger.Printf("Error writing csv: %v\n", err)
-		return ""
+		logger.Debug("Error writing csv", "err", err)
+		return "", errors.New(fmt.Sprintf("Error writing csv: %v", err))
 	}
 
 	for _, e := range entries {
-		w.Write([]string{fmt.Sprintf("%04d", e.Index), e.Title, e.LastModified, e.LastStatus})
+		row := []string{e.Number, e.Title, e.LastModified, e.LastStatus}
+		if withOrigin {
+			row = append([]string{e.Origin}, row...)
+		}
+		w.Write(row)
 		if err := w.Error(); err != nil {
-			logger.Printf("Error writing csv: %v\n", err)
-			return ""
+			logger.Debug("Error writing csv", "err", err)
+			return "", errors.New(fmt.Sprintf("Error writing csv: %v", err))
 		}
 	}
 
 	// have to Flush() explicitly, because only .Write() is used...
 	w.Flush()
 
-	return buf.String()
+	return buf.String(), nil
 }
 
 // ----------------------------------------------------------------------------
@@ -87,6 +143,7 @@ func (CsvExporter) Export(logger *log.Logger, entries []logic.AdrStatus, _ strin
 
 // Empty struct to represent an exporter of json data.
 type JsonAdrData struct {
+	Origin       string `json:"origin,omitempty"`
 	Index        int    `json:"index"`
 	Decision     string `json:"decision"`
 	LastModified string `json:"modifiedDate"`
@@ -95,16 +152,198 @@ type JsonAdrData struct {
 
 type JsonExporter struct{}
 
-func (JsonExporter) Export(logger *log.Logger, entries []logic.AdrStatus, _ string) string {
+func (JsonExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
 	data := make([]JsonAdrData, 0)
 	for _, e := range entries {
-		nextEntry := JsonAdrData{Index: e.Index, Decision: e.Title, LastModified: e.LastModified, LastStatus: e.LastStatus}
+		nextEntry := JsonAdrData{Origin: e.Origin, Index: e.Index, Decision: e.Title, LastModified: e.LastModified, LastStatus: e.LastStatus}
 		data = append(data, nextEntry)
 	}
 
-	jsonData, _ := json.Marshal(data)
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error writing json: %v", err))
+	}
+
+	return string(jsonData), nil
+}
+
+// ----------------------------------------------------------------------------
+// Implementation of an AdrListExporter for JSON data with the complete ADRs
+
+// Empty struct to represent an exporter of the complete ADRs as json data,
+// following the schema logic.AdrCollectionSchema (see document 'Schema').
+type JsonFullExporter struct{}
+
+func (JsonFullExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
+	logger := utils.Logger(ctx)
+	collection := logic.LoadAdrCollection(ctx, entries)
+
+	jsonData, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		logger.Debug("Error writing json", "err", err)
+		return "", errors.New(fmt.Sprintf("Error writing json: %v", err))
+	}
+
+	return string(jsonData), nil
+}
+
+// ----------------------------------------------------------------------------
+// Implementation of an AdrListExporter for YAML data with the complete ADRs
+
+// Empty struct to represent an exporter of the complete ADRs as yaml data,
+// with the same content as the json-full export.
+type YamlExporter struct{}
+
+func (YamlExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
+	logger := utils.Logger(ctx)
+	collection := logic.LoadAdrCollection(ctx, entries)
+
+	buf := new(bytes.Buffer)
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	err := enc.Encode(collection)
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		logger.Debug("Error writing yaml", "err", err)
+		return "", errors.New(fmt.Sprintf("Error writing yaml: %v", err))
+	}
+
+	return buf.String(), nil
+}
+
+// ----------------------------------------------------------------------------
+// Implementation of an AdrListExporter for newline-delimited JSON data
+
+// Empty struct to represent an exporter of the complete ADRs as
+// newline-delimited json, i.e. one ADR (as in the json-full export) per
+// line.
+type NdjsonExporter struct{}
+
+func (NdjsonExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
+	logger := utils.Logger(ctx)
+	collection := logic.LoadAdrCollection(ctx, entries)
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	for _, adr := range collection.Adrs {
+		if err := enc.Encode(adr); err != nil {
+			logger.Debug("Error writing json", "file", adr.Path, "err", err)
+			return "", errors.New(fmt.Sprintf("Error writing json of ADR '%s': %v", adr.Path, err))
+		}
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// ----------------------------------------------------------------------------
+// Implementation of an AdrListExporter for XML data with the complete ADRs
+
+type XmlAdrCollection struct {
+	XMLName xml.Name `xml:"adrs"`
+	Schema  string   `xml:"schema,attr"`
+	Adrs    []XmlAdr `xml:"adr"`
+}
+
+type XmlAdr struct {
+	Origin   string             `xml:"origin,attr,omitempty"`
+	Id       string             `xml:"id,attr"`
+	Index    int                `xml:"index,attr"`
+	Category string             `xml:"category,attr,omitempty"`
+	Archived bool               `xml:"archived,attr"`
+	Title    string             `xml:"title"`
+	Filename string             `xml:"filename"`
+	Path     string             `xml:"path"`
+	Status   []XmlStatusEntry   `xml:"status>entry"`
+	Metadata []XmlMetadataEntry `xml:"metadata>entry"`
+	Sections []XmlSection       `xml:"sections>section"`
+	Links    []XmlLink          `xml:"links>link"`
+	Markdown XmlText            `xml:"markdown"`
+}
+
+// Text written as CDATA section, keeping its line breaks readable.
+type XmlText struct {
+	Value string `xml:",cdata"`
+}
+
+type XmlStatusEntry struct {
+	Date   string `xml:"date,attr"`
+	Status string `xml:"status,attr"`
+}
+
+type XmlMetadataEntry struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type XmlSection struct {
+	Heading  string  `xml:"heading,attr"`
+	Markdown XmlText `xml:"markdown"`
+	Text     XmlText `xml:"text"`
+}
+
+type XmlLink struct {
+	Target string `xml:"target,attr"`
+	Line   int    `xml:"line,attr"`
+	Adr    string `xml:"adr,attr,omitempty"`
+	Text   string `xml:",chardata"`
+}
+
+// Empty struct to represent an exporter of the complete ADRs as xml data,
+// with the same content as the json-full export (except for the
+// configuration).
+type XmlExporter struct{}
+
+func (XmlExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
+	logger := utils.Logger(ctx)
+	collection := logic.LoadAdrCollection(ctx, entries)
+
+	data := XmlAdrCollection{Schema: collection.Schema, Adrs: make([]XmlAdr, 0, len(collection.Adrs))}
+	for _, adr := range collection.Adrs {
+		data.Adrs = append(data.Adrs, toXmlAdr(adr))
+	}
+
+	xmlData, err := xml.MarshalIndent(data, "", "  ")
+	if err != nil {
+		logger.Debug("Error writing xml", "err", err)
+		return "", errors.New(fmt.Sprintf("Error writing xml: %v", err))
+	}
+
+	return xml.Header + string(xmlData), nil
+}
+
+func toXmlAdr(adr logic.AdrDocument) XmlAdr {
+	res := XmlAdr{
+		Origin:   adr.Origin,
+		Id:       adr.Id,
+		Index:    adr.Index,
+		Category: adr.Category,
+		Archived: adr.Archived,
+		Title:    adr.Title,
+		Filename: adr.Filename,
+		Path:     adr.Path,
+		Markdown: XmlText{adr.Markdown},
+	}
+	for _, s := range adr.Status {
+		res.Status = append(res.Status, XmlStatusEntry{Date: s.Date, Status: s.Status})
+	}
+	keys := make([]string, 0, len(adr.Metadata))
+	for k := range adr.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		res.Metadata = append(res.Metadata, XmlMetadataEntry{Key: k, Value: adr.Metadata[k]})
+	}
+	for _, s := range adr.Sections {
+		res.Sections = append(res.Sections, XmlSection{Heading: s.Heading, Markdown: XmlText{s.Markdown}, Text: XmlText{s.Text}})
+	}
+	for _, l := range adr.Links {
+		res.Links = append(res.Links, XmlLink{Target: l.Target, Line: l.Line, Adr: l.Adr, Text: l.Text})
+	}
 
-	return string(jsonData)
+	return res
 }
 
 // ----------------------------------------------------------------------------
@@ -112,24 +351,66 @@ func (JsonExporter) Export(logger *log.Logger, entries []logic.AdrStatus, _ stri
 
 type MarkdownExporter struct{}
 
-func (MarkdownExporter) Export(logger *log.Logger, entries []logic.AdrStatus, dataPath string) string {
+func (MarkdownExporter) Export(ctx context.Context, entries []logic.AdrStatus, dataPath string) (string, error) {
+	source, _ := assembleMarkdownDocument(ctx, entries)
 
-	// resort entries based on their index
+	return string(source), nil
+}
+
+// Assemble all ADRs into a single in-memory markdown document, sorted by
+// their index. If the entries stem from several projects or belong to
+// categories, the ADRs are grouped by project and category, and each group
+// is preceded by a heading with the project's and category's name. Archived
+// ADRs are grouped under the heading "Archived". ADRs
+// with implementing commits or references from source code are followed by
+// lists of these.
+//
+// Returns the document and the set of inserted group headings.
+func assembleMarkdownDocument(ctx context.Context, entries []logic.AdrStatus) ([]byte, map[string]bool) {
+	logger := utils.Logger(ctx)
+
+	// resort entries based on their origin and index
 	sort.Sort(ByIndex(entries))
 
-	// assemble all ADRs into single in-memory document
 	var sb strings.Builder
+	groupHeadings := make(map[string]bool)
 
+	currentGroup := ""
 	for _, e := range entries {
-		buf, err := os.ReadFile(path.Join(dataPath, e.Filename))
+		group := e.Origin
+		if e.Archived {
+			group = strings.TrimPrefix(group+" / Archived", " / ")
+		} else if len(e.Category) > 0 {
+			group = strings.TrimPrefix(group+" / "+e.Category, " / ")
+		}
+		if group != currentGroup {
+			currentGroup = group
+			groupHeadings[currentGroup] = true
+			sb.WriteString("# " + currentGroup + "\n\n")
+		}
+		buf, err := os.ReadFile(e.Path)
 		if err != nil {
-			logger.Printf("Could not read ADR from file '%s': %v\n", e.Filename, err)
+			logger.Warn("Could not read ADR from file", "path", e.Path, "err", err)
 		}
 		sb.Write(buf)
 		sb.WriteString("\n\n")
+		if len(e.Commits) > 0 {
+			sb.WriteString("**Implementing commits**\n\n")
+			for _, c := range e.Commits {
+				sb.WriteString(fmt.Sprintf("* `%s` %s %s (%s)\n", c.Hash[:7], c.Date.Format("2006-01-02"), c.Subject, c.Author))
+			}
+			sb.WriteString("\n")
+		}
+		if len(e.ReferencedFrom) > 0 {
+			sb.WriteString("**Referenced from**\n\n")
+			for _, location := range e.ReferencedFrom {
+				sb.WriteString("* `" + location + "`\n")
+			}
+			sb.WriteString("\n")
+		}
 	}
 
-	return sb.String()
+	return []byte(sb.String()), groupHeadings
 }
 
 // ----------------------------------------------------------------------------
@@ -146,29 +427,28 @@ type HtmlExportVars struct {
 type HtmlExporter struct{}
 
 // ByIndex implements sort.Interface based on the Index field for AdrStatus slices.
+// Entries from different origins and categories are kept together, ordered by
+// the origin's and category's name; archived entries come last.
 type ByIndex []logic.AdrStatus
 
-func (a ByIndex) Len() int           { return len(a) }
-func (a ByIndex) Less(i, j int) bool { return a[i].Index < a[j].Index }
-func (a ByIndex) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-
-func (HtmlExporter) Export(logger *log.Logger, entries []logic.AdrStatus, dataPath string) string {
-
-	// resort entries based on their index
-	sort.Sort(ByIndex(entries))
-
-	// assemble all ADRs into single in-memory document
-	var sb strings.Builder
-
-	for _, e := range entries {
-		buf, err := os.ReadFile(path.Join(dataPath, e.Filename))
-		if err != nil {
-			logger.Printf("Could not read ADR from file '%s': %v\n", e.Filename, err)
-		}
-		sb.Write(buf)
-		sb.WriteString("\n\n")
+func (a ByIndex) Len() int { return len(a) }
+func (a ByIndex) Less(i, j int) bool {
+	if a[i].Origin != a[j].Origin {
+		return a[i].Origin < a[j].Origin
+	}
+	if a[i].Archived != a[j].Archived {
+		return a[j].Archived
+	}
+	if a[i].Category != a[j].Category {
+		return a[i].Category < a[j].Category
 	}
-	source := []byte(sb.String())
+	return a[i].Index < a[j].Index
+}
+func (a ByIndex) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+func (HtmlExporter) Export(ctx context.Context, entries []logic.AdrStatus, dataPath string) (string, error) {
+	logger := utils.Logger(ctx)
+	source, groupHeadings := assembleMarkdownDocument(ctx, entries)
 
 	md := goldmark.New(
 		goldmark.WithExtensions(extension.GFM),
@@ -184,17 +464,18 @@ func (HtmlExporter) Export(logger *log.Logger, entries []logic.AdrStatus, dataPa
 	// create content html
 	var contentBuf bytes.Buffer
 	if err := md.Convert(source, &contentBuf); err != nil {
-		logger.Printf("Could not render ADRs content as html: %v\n", err)
-		return ""
+		logger.Debug("Could not render ADRs content as html", "err", err)
+		return "", errors.New(fmt.Sprintf("Could not render ADRs content as html: %v", err))
 	}
 
 	// create toc html
 	doc := md.Parser().Parse(text.NewReader(source))
 	tree, err := toc.Inspect(doc, source)
 	if err != nil {
-		logger.Printf("Could not render ADRs toc as html: %v\n", err)
-		return ""
+		logger.Debug("Could not render ADRs toc as html", "err", err)
+		return "", errors.New(fmt.Sprintf("Could not render ADRs toc as html: %v", err))
 	}
+	tree.Items = groupTocItems(tree.Items, groupHeadings)
 	list := toc.RenderList(tree)
 
 	var tocBuf bytes.Buffer
@@ -218,8 +499,33 @@ func (HtmlExporter) Export(logger *log.Logger, entries []logic.AdrStatus, dataPa
 	var completeExportBuf bytes.Buffer
 	err = tmpl.Execute(&completeExportBuf, vars)
 	if err != nil {
-		log.Fatal(err)
+		logger.Debug("Could not render HTML export", "err", err)
+		return "", errors.New(fmt.Sprintf("Could not render HTML export: %v", err))
+	}
+
+	return completeExportBuf.String(), nil
+}
+
+// Nest the toc items following a group heading (as inserted by
+// assembleMarkdownDocument) as children of the group heading's item, so
+// that each group becomes a separate section of the navigation.
+func groupTocItems(items toc.Items, groupHeadings map[string]bool) toc.Items {
+	if len(groupHeadings) == 0 {
+		return items
+	}
+
+	res := make(toc.Items, 0)
+	var currentGroup *toc.Item
+	for _, item := range items {
+		if groupHeadings[string(item.Title)] {
+			currentGroup = item
+			res = append(res, item)
+		} else if currentGroup != nil {
+			currentGroup.Items = append(currentGroup.Items, item)
+		} else {
+			res = append(res, item)
+		}
 	}
 
-	return completeExportBuf.String()
+	return res
 }
@@ -0,0 +1,73 @@
+package adrexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Prefix of the names of executables on PATH registered as exporters by
+// RegisterExternalExporters.
+const ExternalExporterPrefix = "adr-go-export-"
+
+// Register each executable 'adr-go-export-<format>' on PATH as exporter of
+// the type <format>. Executables named like an already registered exporter
+// are ignored.
+func RegisterExternalExporters(ctx context.Context) {
+	logger := utils.Logger(ctx)
+	for expType, file := range utils.FindExecutablesWithPrefix(ExternalExporterPrefix) {
+		if _, ok := exporterRegistry[expType]; ok {
+			logger.Debug("Ignoring external exporter, exporter type is registered already", "file", file, "format", expType)
+			continue
+		}
+		logger.Debug("Registering external exporter", "file", file, "format", expType)
+		executable := file
+		RegisterExporter(expType, func() AdrListExporter { return ExternalExporter{File: executable} })
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Implementation of an AdrListExporter running an external program
+
+// Exporter running the executable File, which gets the complete ADRs (as in
+// the json-full export) on stdin and writes the export to stdout. Its
+// messages on stderr are passed through; a non-zero exit status makes the
+// export fail.
+type ExternalExporter struct {
+	File string
+}
+
+func (ee ExternalExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
+	logger := utils.Logger(ctx)
+	input, err := json.Marshal(logic.LoadAdrCollection(ctx, entries))
+	if err != nil {
+		logger.Debug("Could not write ADRs for external exporter", "err", err)
+		return "", errors.New(fmt.Sprintf("Could not write ADRs for external exporter '%s': %v", ee.File, err))
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, ee.File)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	cmd.Env = utils.PluginEnvironment()
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		logger.Debug("External exporter failed", "file", ee.File, "exitCode", exitErr.ExitCode())
+		return "", errors.New(fmt.Sprintf("External exporter '%s' failed with exit status %d", ee.File, exitErr.ExitCode()))
+	}
+	if err != nil {
+		logger.Debug("Could not run external exporter", "file", ee.File, "err", err)
+		return "", errors.New(fmt.Sprintf("Could not run external exporter '%s': %v", ee.File, err))
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,178 @@
+package adrexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/logic"
+	"github.com/dukemarty/adr-go/utils"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+// Extension of the templates registered as exporters by
+// RegisterTemplateExporters.
+const TemplateExtension = ".tmpl"
+
+// Register each template (file '*.tmpl') in the folder as exporter, with
+// the filename without extension as type; e.g. 'confluence.tmpl' becomes
+// the exporter 'confluence'. Templates named like an already registered
+// exporter are ignored. A missing folder is no error.
+func RegisterTemplateExporters(ctx context.Context, folder string) error {
+	logger := utils.Logger(ctx)
+	files, err := filepath.Glob(filepath.Join(folder, "*"+TemplateExtension))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		expType := strings.ToLower(strings.TrimSuffix(filepath.Base(file), TemplateExtension))
+		if _, ok := exporterRegistry[expType]; ok {
+			logger.Warn("Ignoring exporter template, exporter type is registered already", "file", file, "format", expType)
+			continue
+		}
+		logger.Debug("Registering exporter template", "file", file, "format", expType)
+		templateFile := file
+		RegisterExporter(expType, func() AdrListExporter { return TemplateExporter{File: templateFile} })
+	}
+
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// Implementation of an AdrListExporter rendering a user-defined template
+
+// Exporter rendering the text/template in File over the complete ADRs
+// (logic.AdrCollection, as in the json-full export), with the functions of
+// TemplateFunctions.
+type TemplateExporter struct {
+	File string
+}
+
+func (te TemplateExporter) Export(ctx context.Context, entries []logic.AdrStatus, _ string) (string, error) {
+	logger := utils.Logger(ctx)
+	content, err := os.ReadFile(te.File)
+	if err != nil {
+		logger.Debug("Could not read exporter template", "file", te.File, "err", err)
+		return "", errors.New(fmt.Sprintf("Could not read exporter template '%s': %v", te.File, err))
+	}
+	tmpl, err := template.New(filepath.Base(te.File)).Funcs(TemplateFunctions()).Parse(string(content))
+	if err != nil {
+		logger.Debug("Could not parse exporter template", "file", te.File, "err", err)
+		return "", errors.New(fmt.Sprintf("Could not parse exporter template '%s': %v", te.File, err))
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, logic.LoadAdrCollection(ctx, entries))
+	if err != nil {
+		logger.Debug("Could not render exporter template", "file", te.File, "err", err)
+		return "", errors.New(fmt.Sprintf("Could not render exporter template '%s': %v", te.File, err))
+	}
+
+	return buf.String(), nil
+}
+
+// Get the helper functions available in exporter templates, in addition to
+// the predefined functions of text/template:
+//
+//	lower, upper, title   change the case of a string
+//	trim                  remove leading and trailing white space
+//	replace old new s     replace all occurrences of old in s by new
+//	contains s sub        check if s contains sub
+//	hasPrefix s prefix    check if s starts with prefix
+//	split s sep           split s at sep into a list
+//	join list sep         join a list of strings with sep
+//	indent n s            indent each line of s by n spaces
+//	add a b               sum of two numbers, e.g. for counters
+//	now                   the current date (2006-01-02)
+//	formatDate layout d   reformat a date (2006-01-02) with the Go layout
+//	lastStatus adr        the last status entry of an ADR (.Date, .Status)
+//	section adr heading   the section of an ADR (.Markdown, .Text) by heading
+//	withStatus s adrs     the ADRs whose last status is s
+//	markdownToHtml md     render markdown as HTML
+//	xmlEscape s           escape s for XML (and HTML) content
+//	toJson v, toYaml v    render a value as JSON or YAML
+func TemplateFunctions() template.FuncMap {
+	return template.FuncMap{
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"title":     cases.Title(language.English).String,
+		"trim":      strings.TrimSpace,
+		"replace":   func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":  strings.Contains,
+		"hasPrefix": strings.HasPrefix,
+		"split":     strings.Split,
+		"join":      func(list []string, sep string) string { return strings.Join(list, sep) },
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"add": func(a int, b int) int { return a + b },
+		"now": func() string { return time.Now().Format("2006-01-02") },
+		"formatDate": func(layout string, date string) string {
+			t, err := time.Parse("2006-01-02", date)
+			if err != nil {
+				return date
+			}
+			return t.Format(layout)
+		},
+		"lastStatus": lastStatusOfDocument,
+		"section": func(adr logic.AdrDocument, heading string) logic.AdrSection {
+			for _, s := range adr.Sections {
+				if strings.EqualFold(s.Heading, heading) {
+					return s
+				}
+			}
+			return logic.AdrSection{Heading: heading}
+		},
+		"withStatus": func(status string, adrs []logic.AdrDocument) []logic.AdrDocument {
+			res := make([]logic.AdrDocument, 0)
+			for _, adr := range adrs {
+				if strings.EqualFold(lastStatusOfDocument(adr).Status, status) {
+					res = append(res, adr)
+				}
+			}
+			return res
+		},
+		"markdownToHtml": func(md string) (string, error) {
+			var buf bytes.Buffer
+			err := goldmark.New(goldmark.WithExtensions(extension.GFM)).Convert([]byte(md), &buf)
+			return buf.String(), err
+		},
+		"xmlEscape": func(s string) (string, error) {
+			var buf bytes.Buffer
+			err := xml.EscapeText(&buf, []byte(s))
+			return buf.String(), err
+		},
+		"toJson": func(v any) (string, error) {
+			res, err := json.MarshalIndent(v, "", "  ")
+			return string(res), err
+		},
+		"toYaml": func(v any) (string, error) {
+			res, err := yaml.Marshal(v)
+			return string(res), err
+		},
+	}
+}
+
+// Get the last status entry of the ADR, or an empty one if it has none.
+func lastStatusOfDocument(adr logic.AdrDocument) data.StatusChange {
+	if len(adr.Status) == 0 {
+		return data.StatusChange{}
+	}
+
+	return adr.Status[len(adr.Status)-1]
+}
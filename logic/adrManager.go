@@ -4,12 +4,14 @@ Copyright © 2023 Martin Loesch <development@martinloesch.net>
 package logic
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"path"
 
 	// "io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -24,7 +26,13 @@ import (
 	"github.com/dukemarty/adr-go/utils"
 )
 
-var configFileName = ".adr.json"
+var configFileName = data.ConfigFilename
+
+// Number of further ADR numbers tried if the number for a new ADR turns out
+// to be in use already.
+const maxCreateAttempts = 100
+
+var leadingDigitsRegex = regexp.MustCompile(`^\d+`)
 
 var defaultTemplate = `# {{.NUMBER}}. {{.TITLE}}
 
@@ -48,6 +56,9 @@ Consequences here...`
 
 type AdrManager struct {
 	Config data.Configuration
+	// Root directory of the ADR project, i.e. the directory containing the
+	// config file. Empty for the current working directory.
+	BaseDir string
 }
 
 // Constructor for a new AdrManager object with a given configuration config.
@@ -63,26 +74,70 @@ func NewAdrManager(config data.Configuration) *AdrManager {
 // Constructor for an AdrManager based on a stored (initialized) ADR setup
 // in the current directory.
 // Return either the constructed AdrManager, or an error if it could not be opened.
-func OpenAdrManager(logger *log.Logger) (*AdrManager, error) {
-	config, err := data.LoadConfiguration()
+//
+// If an ADR log was selected with SelectAdrLog, that one is opened instead.
+func OpenAdrManager(ctx context.Context) (*AdrManager, error) {
+	am, err := OpenAdrManagerAt(ctx, selectedLogDir)
+	if err == nil {
+		utils.StartJournal(am.DataDirectory())
+	}
+
+	return am, err
+}
+
+// Constructor for an AdrManager based on a stored (initialized) ADR setup
+// in the directory baseDir.
+// Return either the constructed AdrManager, or an error if it could not be opened.
+func OpenAdrManagerAt(ctx context.Context, baseDir string) (*AdrManager, error) {
+	logger := utils.Logger(ctx)
+	config, err := data.LoadConfigurationFrom(baseDir)
 	if err != nil {
-		logger.Printf("Could not load ADR configuration, maybe project is not initialized: %v\n", err)
-		return nil, errors.New("Could not load ADR configuration.")
+		logger.Debug("Could not load ADR configuration, maybe project is not initialized", "err", err)
+		return nil, &data.NotInitializedError{Dir: baseDir, Err: err}
 	}
 
 	am := AdrManager{
-		Config: config,
+		Config:  config,
+		BaseDir: baseDir,
 	}
 
 	return &am, nil
 }
 
+// Store the (changed) configuration of the ADR project.
+func (am AdrManager) StoreConfiguration() error {
+	return am.Config.Store(filepath.Join(am.BaseDir, configFileName))
+}
+
+// Get the path of the folder containing adr-go's internal data, like the
+// journal of operations and the lock file.
+func (am AdrManager) DataDirectory() string {
+	return filepath.Join(am.BaseDir, utils.DataFolder)
+}
+
+// Get the path of the folder containing the ADRs, taking into account
+// the project's base directory.
+func (am AdrManager) AdrDirectory() string {
+	return filepath.Join(am.BaseDir, am.Config.Path)
+}
+
+// Get the path of the folder with the templates of additional export
+// formats, as configured or the subfolder 'exporters' of the ADR folder.
+func (am AdrManager) ExporterTemplatesDirectory() string {
+	if len(am.Config.ExporterTemplates) > 0 {
+		return filepath.Join(am.BaseDir, am.Config.ExporterTemplates)
+	}
+
+	return filepath.Join(am.AdrDirectory(), "exporters")
+}
+
 // Initialize ADR management in the current directory. Logging is performed
-// via the logger provided as parameter, allowing to better control how much
+// via the logger carried by the context, allowing to better control how much
 // logging is done.
 // Returns an error if for any reason initialization could not be performed,
 // in particular if it was already initialized this counts as an error.
-func (am AdrManager) Init(logger *log.Logger) error {
+func (am AdrManager) Init(ctx context.Context) error {
+	logger := utils.Logger(ctx)
 
 	if utils.FileExists(configFileName) {
 		return errors.New("ADRs seem to be initialized already, config file '.adr.json' exists!")
@@ -93,7 +148,7 @@ func (am AdrManager) Init(logger *log.Logger) error {
 
 	// 2) Create directory for ADRs
 	if _, err := os.Stat(am.Config.Path); os.IsNotExist(err) {
-		if err := os.MkdirAll(am.Config.Path, os.ModePerm); err != nil {
+		if err := utils.MkdirAll(am.Config.Path); err != nil {
 			return errors.New(fmt.Sprintf("Error when trying to create directory for adr's: %v", err))
 		}
 	}
@@ -105,78 +160,250 @@ func (am AdrManager) Init(logger *log.Logger) error {
 	}
 	pathShortTemplate := filepath.Join(am.Config.Path, "template-short.md")
 	pathlongTemplate := filepath.Join(am.Config.Path, "template-long.md")
-	errShort := os.WriteFile(pathShortTemplate, []byte(val.Short), 0644)
+	errShort := utils.WriteFile(pathShortTemplate, []byte(val.Short), 0644)
 	if errShort != nil {
-		logger.Printf("Error when writing short ADR template: %v\n", errShort)
+		logger.Warn("Error when writing short ADR template", "err", errShort)
 	}
-	errLong := os.WriteFile(pathlongTemplate, []byte(val.Long), 0644)
+	errLong := utils.WriteFile(pathlongTemplate, []byte(val.Long), 0644)
 	if errLong != nil {
-		logger.Printf("Error when writing long ADR template: %v\n", errLong)
+		logger.Warn("Error when writing long ADR template", "err", errLong)
 	}
 	if errShort == nil && errLong == nil {
-		logger.Println("Successfully wrote short and long ADR templates.")
+		logger.Debug("Successfully wrote short and long ADR templates")
 	}
 
 	return nil
 }
 
-func (am AdrManager) AddAdr(title string, logger *log.Logger) (string, error) {
-	templateContent := am.loadTemplateOrDefault(filepath.Join(am.Config.Path, am.Config.TemplateName), logger)
-	return am.AddAdrWithContent(title, templateContent, logger)
+func (am AdrManager) AddAdr(ctx context.Context, title string, category string) (string, error) {
+	return am.AddAdrFromTemplate(ctx, title, category, am.Config.TemplateName)
 }
 
-func (am AdrManager) AddAdrFromTemplate(title string, templateFile string, logger *log.Logger) (string, error) {
-	templContent := am.loadTemplateOrDefault(templateFile, logger)
+func (am AdrManager) AddAdrFromTemplate(ctx context.Context, title string, category string, templateFile string) (string, error) {
+	templContent := am.loadTemplateOrDefault(ctx, templateFile)
+
+	adrFile, err := am.AddAdrToCategoryWithContent(ctx, title, category, templContent)
+	var parseError *data.ParseError
+	if errors.As(err, &parseError) && parseError.File == contentTemplateName {
+		parseError.File = templateFile
+	}
 
-	return am.AddAdrWithContent(title, templContent, logger)
+	return adrFile, err
 }
 
+// Name of the content of a new ADR in errors about it as template.
+const contentTemplateName = "ADR template"
+
 // Add new ADR with the provided title and the also provided content.
 // The content is processed as a template, replacing variables. It
 // also generates/updates the TOC file.
 //
 // The replaced variables are '{.NUMBER}', '{.TITLE}' and '{.DATE}'.
-func (am AdrManager) AddAdrWithContent(title string, content string, logger *log.Logger) (string, error) {
-	newDate := createDateString()
-	index := am.getNewIndexString(logger)
-	fileName := constructFilenameFromIndexAndTitle(index, title)
+//
+// Returns the path of the new ADR file, or an error; a data.ParseError if
+// the content is no valid template.
+func (am AdrManager) AddAdrWithContent(ctx context.Context, title string, content string) (string, error) {
+	return am.AddAdrToCategoryWithContent(ctx, title, "", content)
+}
 
-	// 	let newIndex = Utils.getNewIndexString()
-	// 	let fileData = raw.replace(/{NUMBER}/g, Utils.getLatestIndex() + 1)
-	// 	  .replace(/{TITLE}/g, name)
-	// 	  .replace(/{DATE}/g, newDate)
-	vars := data.AdrVars{
-		NUMBER: index,
-		TITLE:  title,
-		DATE:   newDate,
+// Add new ADR like AddAdrWithContent, but into the subfolder of the given
+// category, which is created if necessary. The ADR is numbered according
+// to the category's number sequence, see data.Configuration.NumberingOfCategory.
+func (am AdrManager) AddAdrToCategoryWithContent(ctx context.Context, title string, category string, content string) (string, error) {
+	logger := utils.Logger(ctx)
+	category = filepath.ToSlash(filepath.Clean(category))
+	if category == "." {
+		category = ""
+	}
+	if strings.HasPrefix(category, "..") || filepath.IsAbs(category) {
+		return "", errors.New(fmt.Sprintf("Invalid category '%s', must be a subfolder of the ADR folder", category))
+	}
+	if len(category) > 0 {
+		err := utils.MkdirAll(filepath.Join(am.AdrDirectory(), category))
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("Could not create folder for category '%s': %v", category, err))
+		}
 	}
-	logger.Printf("Identified template variables: %v\n", vars)
 
 	tmpl, err := template.New("adr").Parse(content)
 	if err != nil {
-		panic(err)
+		logger.Debug("Could not parse ADR template", "err", err)
+		return "", &data.ParseError{File: contentTemplateName, Reason: err.Error()}
+	}
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	newDate := createDateString()
+	number := am.getNewIndex(ctx, category)
+	var fileName string
+	// The lock keeps other adr-go processes from using the same number; the
+	// exclusive creation and the retries guard against those not honoring it
+	// (e.g. after a stale lock was removed).
+	for attempt := 0; ; attempt++ {
+		index := am.createIndexForCategory(ctx, category, number)
+		fileName = filepath.Join(category, constructFilenameFromIndexAndTitle(index, title))
+
+		// 	let newIndex = Utils.getNewIndexString()
+		// 	let fileData = raw.replace(/{NUMBER}/g, Utils.getLatestIndex() + 1)
+		// 	  .replace(/{TITLE}/g, name)
+		// 	  .replace(/{DATE}/g, newDate)
+		vars := data.AdrVars{
+			NUMBER: index,
+			TITLE:  title,
+			DATE:   newDate,
+		}
+		logger.Debug("Identified template variables", "vars", vars)
+
+		if am.isIndexInUse(ctx, category, index) {
+			err = fs.ErrExist
+		} else {
+			err = am.createAdrFile(am.AdrDirectory(), fileName, tmpl, vars)
+		}
+		if err == nil {
+			break
+		}
+		var parseError *data.ParseError
+		if errors.As(err, &parseError) {
+			return "", err
+		}
+		if !errors.Is(err, fs.ErrExist) || attempt >= maxCreateAttempts {
+			return "", errors.New(fmt.Sprintf("Could not create ADR file '%s': %v", fileName, err))
+		}
+		logger.Debug("ADR number is already used, retrying with the next one", "index", index)
+		number++
+	}
+
+	toc := am.GenerateToc(ctx)
+	utils.WriteFile(filepath.Join(am.AdrDirectory(), "README.md"), []byte(toc), 0644)
+
+	return filepath.Join(am.AdrDirectory(), fileName), nil
+}
+
+// Get the filenames of all ADRs, relative to the ADR folder. ADRs in
+// subfolders (categories) are included, hidden folders and the archive
+// folder are skipped.
+func (am AdrManager) GetAllAdrFileNames(ctx context.Context) ([]string, error) {
+	return am.collectAdrFileNames(ctx, am.AdrDirectory())
+}
+
+// Get the filenames of all archived ADRs, relative to the ADR folder (i.e.
+// starting with the archive folder).
+func (am AdrManager) GetArchivedAdrFileNames(ctx context.Context) ([]string, error) {
+	archiveDir := filepath.Join(am.AdrDirectory(), data.ArchiveFolder)
+	if !utils.FileExists(archiveDir) {
+		return []string{}, nil
+	}
+
+	return am.collectAdrFileNames(ctx, archiveDir)
+}
+
+// Get the filenames of all ADRs, the active ones first, followed by the
+// archived ones.
+func (am AdrManager) GetAllAdrFileNamesIncludingArchive(ctx context.Context) ([]string, error) {
+	res, err := am.GetAllAdrFileNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := am.GetArchivedAdrFileNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(res, archived...), nil
+}
+
+// Collect the ADR files below the directory dir, with names relative to
+// the ADR folder. In a dry run, its changes are taken into account.
+func (am AdrManager) collectAdrFileNames(ctx context.Context, dir string) ([]string, error) {
+	logger := utils.Logger(ctx)
+	adrDir := am.AdrDirectory()
+	created, removed := utils.PendingFiles(dir)
+	if _, err := os.Stat(dir); err != nil && len(created) == 0 {
+		return nil, err
 	}
+	archiveDir := filepath.Join(adrDir, data.ArchiveFolder)
 
-	am.createAdrFile(am.Config.Path, fileName, tmpl, vars)
+	res := make([]string, 0)
+	absAdrDir, _ := filepath.Abs(adrDir)
+	for _, p := range created {
+		rel, _ := filepath.Rel(absAdrDir, p)
+		inArchive := data.IsArchivedAdrFile(rel) && dir != archiveDir
+		if am.isAdrFileName(filepath.Base(p)) && !inArchive && !strings.Contains(filepath.ToSlash(rel), "/.") {
+			res = append(res, rel)
+		}
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return res, nil
+	}
+	err := filepath.WalkDir(dir, func(p string, file fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file.IsDir() {
+			if p != dir && (strings.HasPrefix(file.Name(), ".") || p == archiveDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		logger.Debug("Analyzing file", "name", file.Name(), "isDir", file.IsDir(), "ext", filepath.Ext(file.Name()))
+		if am.isAdrFileName(file.Name()) {
+			abs, _ := filepath.Abs(p)
+			if removed[abs] {
+				return nil
+			}
+			rel, _ := filepath.Rel(adrDir, p)
+			res = append(res, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	toc := am.GenerateToc(logger)
-	os.WriteFile(filepath.Join(am.Config.Path, "README.md"), []byte(toc), 0644)
+	return res, nil
+}
 
-	return fileName, nil
+// Check if the file name is the name of an ADR, i.e. a markdown file
+// which is neither the TOC nor a template.
+func (am AdrManager) isAdrFileName(name string) bool {
+	return name != "README.md" && !strings.HasPrefix(name, "template-") && name != am.Config.TemplateName && filepath.Ext(name) == ".md"
 }
 
-func (am AdrManager) GetAllAdrFileNames(logger *log.Logger) ([]string, error) {
-	files, err := os.ReadDir(am.Config.Path)
+// Get the filenames of all ADRs which contain all of the provided keywords.
+// The keywords are treated as regular expressions; a data.InvalidKeywordError
+// is returned for a keyword which is no valid one.
+func (am AdrManager) GetAdrFilenamesFiltered(ctx context.Context, keywords []string, caseSensitive bool) ([]string, error) {
+	logger := utils.Logger(ctx)
+	regexes, err := compileKeywordsToRegexes(keywords, caseSensitive)
 	if err != nil {
+		logger.Debug("Error compiling regexes for keyword search", "err", err)
 		return nil, err
 	}
+	allAdrFiles, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		logger.Warn("Error loading ADR file names", "err", err)
+	}
 
 	res := make([]string, 0)
-	for _, file := range files {
-		logger.Printf("Analyzing file %v, Name='%s', IsDir=%v with file extension='%s'\n", file, file.Name(), file.IsDir(), filepath.Ext(file.Name()))
-		if !file.IsDir() && file.Name() != "README.md" && !strings.HasPrefix(file.Name(), "template-") && file.Name() != am.Config.TemplateName && filepath.Ext(file.Name()) == ".md" {
-			res = append(res, file.Name())
+FILELOOP:
+	for _, adrFile := range allAdrFiles {
+		rawContent, err := utils.ReadFile(filepath.Join(am.AdrDirectory(), adrFile))
+		if err != nil {
+			logger.Debug("Error reading ADR", "file", adrFile, "err", err)
+			return nil, err
 		}
+		content := string(rawContent)
+		for _, r := range regexes {
+			if !r.MatchString(content) {
+				continue FILELOOP
+			}
+		}
+
+		res = append(res, adrFile)
 	}
 
 	return res, nil
@@ -191,32 +418,86 @@ func constructFilenameFromIndexAndTitle(index string, title string) string {
 // Get an ADR's filename for a given index number adrIndex.
 // Returns either the found filename, or an error object if it could not find
 // the respective ADR.
-func (am AdrManager) GetAdrFilenameByIndex(adrIndex int, logger *log.Logger) (string, error) {
-	allAdrFiles, err := am.GetAllAdrFileNames(logger)
+func (am AdrManager) GetAdrFilenameByIndex(ctx context.Context, adrIndex int) (string, error) {
+	logger := utils.Logger(ctx)
+	allAdrFiles, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		logger.Debug("Could not read any ADRs, in particular not found", "index", adrIndex, "err", err)
+		return "", err
+	}
+
+	// ADRs of the global number sequence take precedence over ADRs of
+	// categories with their own sequence
+	for _, ownSequence := range []bool{false, true} {
+		for _, filename := range allAdrFiles {
+			_, _, own := am.Config.NumberingOfCategory(data.CategoryOfAdrFile(filename))
+			if own != ownSequence {
+				continue
+			}
+			index, err := am.ExtractAdrIndexFromFile(filename)
+			if err != nil {
+				continue
+			}
+			if index == adrIndex {
+				return filename, nil
+			}
+		}
+	}
+
+	return "", &data.AdrNotFoundError{Id: strconv.Itoa(adrIndex)}
+}
+
+// Get an ADR's filename for a given id, which is either the plain index
+// number, the number including a prefix (e.g. "SEC-001"), or a category
+// and a number (e.g. "security/1").
+// Returns either the found filename, or an error object if it could not find
+// the respective ADR.
+func (am AdrManager) GetAdrFilenameById(ctx context.Context, adrId string) (string, error) {
+	logger := utils.Logger(ctx)
+	if adrIndex, err := strconv.Atoi(adrId); err == nil {
+		return am.GetAdrFilenameByIndex(ctx, adrIndex)
+	}
+
+	allAdrFiles, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
 	if err != nil {
-		logger.Printf("Could not read any ADRs, in particular not found index %d: %v\n", adrIndex, err)
+		logger.Debug("Could not read any ADRs, in particular not found", "id", adrId, "err", err)
 		return "", err
 	}
 
 	for _, filename := range allAdrFiles {
-		index, err := am.ExtractAdrIndexFromFile(filename)
+		category := data.CategoryOfAdrFile(filename)
+		prefix, _, _ := am.Config.NumberingOfCategory(category)
+		var numberPart string
+		if len(category) > 0 && strings.HasPrefix(adrId, category+"/") {
+			numberPart = strings.TrimPrefix(adrId, category+"/")
+		} else if len(prefix) > 0 && strings.HasPrefix(strings.ToUpper(adrId), strings.ToUpper(prefix)) {
+			numberPart = adrId[len(prefix):]
+		} else {
+			continue
+		}
+		adrIndex, err := strconv.Atoi(numberPart)
 		if err != nil {
 			continue
 		}
-		if index == adrIndex {
+		index, err := am.ExtractAdrIndexFromFile(filename)
+		if err == nil && index == adrIndex {
 			return filename, nil
 		}
 	}
 
-	return "", errors.New(fmt.Sprintf("Could not find ADR with index %d", adrIndex))
+	return "", &data.AdrNotFoundError{Id: adrId}
 }
 
-func (am AdrManager) loadTemplateOrDefault(templateFile string, logger *log.Logger) string {
-	rawTemplate, err := os.ReadFile(filepath.Join(am.Config.Path, templateFile))
+func (am AdrManager) loadTemplateOrDefault(ctx context.Context, templateFile string) string {
+	logger := utils.Logger(ctx)
+	rawTemplate, err := utils.ReadFile(filepath.Join(am.AdrDirectory(), templateFile))
+	if err != nil {
+		logger.Debug("Could not read requested template file", "template", templateFile, "err", err)
+		rawTemplate, err = readTemplateFromCentralStore(ctx, templateFile)
+	}
 	var templContent string
 	if err != nil {
-		logger.Printf("Could not read requested template file %s: %v\n", templateFile, err)
-		logger.Println("Use standard template instead!")
+		logger.Warn("Could not read requested template file, neither in the ADR folder nor in the central store; using standard template", "template", templateFile, "err", err)
 		val, _ := templates.TemplatesLibrary["en"]
 		templContent = val.Short
 	} else {
@@ -227,120 +508,231 @@ func (am AdrManager) loadTemplateOrDefault(templateFile string, logger *log.Logg
 }
 
 // Generate table of content of all found ADRs and return
-// it as a string.
-func (am AdrManager) GenerateToc(logger *log.Logger) string {
-	var sb strings.Builder
-
-	// header
-	sb.WriteString("# Architecture Decision Records\n\n")
-
-	// body
-	adrs, err := am.GetAllAdrFileNames(logger)
+// it as a string. Withdrawn ADRs are left out, archived ADRs are listed in
+// a section of their own.
+func (am AdrManager) GenerateToc(ctx context.Context) string {
+	logger := utils.Logger(ctx)
+	// ADRs without category first, then one section per category
+	adrs, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
 	if err != nil {
 
 	}
 	sort.Strings(adrs)
+	sections := make(tocSections)
 	for _, fn := range adrs {
-		adrInfos, err := data.LoadAdrInfo(logger, am.Config.Path, fn)
-		if err == nil {
-			entry := "\n* [" + strconv.Itoa(adrInfos.Index) + ". " + adrInfos.Title + "](" + adrInfos.RelativePath + ")"
-			sb.WriteString(entry)
+		adrInfos, err := data.LoadAdrInfo(ctx, am.AdrDirectory(), fn)
+		if err != nil {
+			continue
+		}
+		status, err := data.ReadStatusEntries(ctx, adrInfos.RelativePath)
+		if err == nil && len(status) > 0 && strings.EqualFold(status[len(status)-1].Status, data.WithdrawnStatus) {
+			logger.Debug("Leaving withdrawn ADR out of TOC", "file", fn)
+			continue
+		}
+		entry := tocEntry{
+			Path:   adrInfos.RelativePath,
+			Label:  am.tocLabel(adrInfos),
+			Line:   "* [" + am.tocLabel(adrInfos) + ". " + adrInfos.Title + "](" + adrInfos.RelativePath + ")",
+			Nested: am.generateCrossLogTocEntries(ctx, adrInfos.RelativePath),
 		}
+		section := adrInfos.Category
+		if adrInfos.Archived {
+			section = tocArchiveSection
+		}
+		sections[section] = append(sections[section], entry)
+	}
+
+	return renderToc(sections)
+}
+
+func (am AdrManager) displayId(adrInfos data.AdrInfo) string {
+	if _, _, own := am.Config.NumberingOfCategory(adrInfos.Category); own {
+		return adrInfos.Id
 	}
 
-	// footer
-	sb.WriteString("\n")
+	return fmt.Sprintf("%0*d", am.Config.Digits, adrInfos.Index)
+}
+
+// Get the number of an ADR with the prefix and digits configured for its
+// category.
+func (am AdrManager) numberedId(adrInfos data.AdrInfo) string {
+	prefix, digits, _ := am.Config.NumberingOfCategory(adrInfos.Category)
+
+	return fmt.Sprintf("%s%0*d", prefix, digits, adrInfos.Index)
+}
+
+// Label of an ADR in the TOC: its index, or its complete id for categories
+// with their own number sequence.
+func (am AdrManager) tocLabel(adrInfos data.AdrInfo) string {
+	if _, _, own := am.Config.NumberingOfCategory(adrInfos.Category); own {
+		return adrInfos.Id
+	}
 
-	return sb.String()
+	return strconv.Itoa(adrInfos.Index)
+}
+
+// Generate nested TOC entries for all references to ADRs in other ADR
+// logs contained in the ADR file adrPath.
+func (am AdrManager) generateCrossLogTocEntries(ctx context.Context, adrPath string) []string {
+	logger := utils.Logger(ctx)
+	res := make([]string, 0)
+	content, err := utils.ReadFile(adrPath)
+	if err != nil {
+		return res
+	}
+
+	for _, ref := range FindCrossLogReferences(string(content)) {
+		info, err := ResolveCrossLogReference(ctx, ref)
+		if err != nil {
+			logger.Warn("Could not resolve ADR reference for TOC", "ref", ref, "err", err)
+			continue
+		}
+		res = append(res, "  * see ["+ref+" "+info.Title+"]("+relativeLink(am.AdrDirectory(), info.RelativePath)+")")
+	}
+
+	return res
 }
 
 type AdrStatus struct {
-	Filename     string
-	Index        int
+	Filename string
+	// Path of the ADR file, including the ADR directory.
+	Path string
+	// Name of the project the ADR belongs to if it was loaded as part of
+	// a combined view, empty otherwise.
+	Origin   string
+	Category string
+	// Id of the ADR for display: the zero-padded index, or the complete id
+	// (with prefix) for categories with their own number sequence.
+	Id    string
+	Index int
+	// Number of the ADR with the prefix and digits configured for its
+	// category, e.g. 'ADR-0004'.
+	Number       string
 	Title        string
 	LastModified string
 	LastStatus   string
+	// Set if the ADR is in the archive folder.
+	Archived bool
+	// Commits implementing the ADR, only filled if requested (see
+	// AddImplementingCommits).
+	Commits []utils.GitCommit
+	// Source locations ('file:line') referencing the ADR, only filled if
+	// requested (see AddReferences).
+	ReferencedFrom []string
 }
 
-func (am AdrManager) GetListOfAllAdrsStatus(logger *log.Logger) ([]AdrStatus, error) {
-	allAdrFiles, err := am.GetAllAdrFileNames(logger)
+func (am AdrManager) GetListOfAllAdrsStatus(ctx context.Context) ([]AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	allAdrFiles, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
 	if err != nil {
-		logger.Printf("Could not load ADR files: %v\n", err)
+		logger.Debug("Could not load ADR files", "err", err)
 		return nil, err
 	}
 
-	return am.GetStatusFromListOfAdrFiles(allAdrFiles, logger)
+	return am.GetStatusFromListOfAdrFiles(ctx, allAdrFiles)
 }
 
-func (am AdrManager) GetStatusFromListOfAdrFiles(files []string, logger *log.Logger) ([]AdrStatus, error) {
+func (am AdrManager) GetStatusFromListOfAdrFiles(ctx context.Context, files []string) ([]AdrStatus, error) {
+	logger := utils.Logger(ctx)
 	res := make([]AdrStatus, 0)
 	for _, filename := range files {
-		adrInfos, err := data.LoadAdrInfo(logger, am.Config.Path, filename)
+		adrInfos, err := data.LoadAdrInfo(ctx, am.AdrDirectory(), filename)
 		if err != nil {
-			logger.Printf("Error loading basic info for %s\n", filename)
+			logger.Warn("Error loading basic info of ADR", "file", filename, "err", err)
 			continue
 		}
-		status, err := data.ReadStatusEntries(logger, adrInfos.RelativePath)
+		status, err := data.ReadStatusEntries(ctx, adrInfos.RelativePath)
 		if err != nil {
-			logger.Printf("Error loading status for %s\n", filename)
+			logger.Warn("Error loading status of ADR", "file", filename, "err", err)
 			continue
 		}
-		res = append(res, AdrStatus{Filename: filename, Index: adrInfos.Index, Title: adrInfos.Title, LastModified: status[len(status)-1].Date, LastStatus: status[len(status)-1].Status})
+		res = append(res, AdrStatus{Filename: filename, Path: adrInfos.RelativePath, Category: adrInfos.Category, Id: am.displayId(adrInfos), Index: adrInfos.Index, Number: am.numberedId(adrInfos), Title: adrInfos.Title, LastModified: status[len(status)-1].Date, LastStatus: status[len(status)-1].Status, Archived: adrInfos.Archived})
 	}
 
 	return res, nil
 }
 
+// Extract the index of an ADR from its filename (relative to the ADR folder),
+// taking into account the prefix of the ADR's category.
 func (am AdrManager) ExtractAdrIndexFromFile(filename string) (int, error) {
-	indexPart := filename[:am.Config.Digits]
+	prefix, _, _ := am.Config.NumberingOfCategory(data.CategoryOfAdrFile(filename))
+	indexPart := leadingDigitsRegex.FindString(strings.TrimPrefix(filepath.Base(filename), prefix))
 	index, err := strconv.Atoi(indexPart)
 	if err != nil {
-		log.Printf("Could not parse '%s' as index: %v\n", indexPart, err)
 		return -1, err
 	}
 
 	return index, nil
 }
 
-func (am AdrManager) UpdateFilenameByTitle(filename string, logger *log.Logger) error {
-	adrInfos, err := data.LoadAdrInfo(logger, am.Config.Path, filename)
+// Rename an ADR file (relative to the ADR folder) if its name does not fit
+// to the ADR's index and title.
+// Returns the new filename (the old one if no renaming was necessary), or
+// an error.
+func (am AdrManager) UpdateFilenameByTitle(ctx context.Context, filename string) (string, error) {
+	logger := utils.Logger(ctx)
+	adrInfos, err := data.LoadAdrInfo(ctx, am.AdrDirectory(), filename)
 	if err != nil {
-		logger.Printf("Error loading title from ADR: %v\n", err)
-		return errors.New(fmt.Sprintf("Error loading title from ADR: %v", err))
+		logger.Debug("Error loading title from ADR", "err", err)
+		return filename, errors.New(fmt.Sprintf("Error loading title from ADR: %v", err))
 	}
 
-	newFilename := constructFilenameFromIndexAndTitle(am.createIndexByNumber(adrInfos.Index, logger), strings.TrimSpace(adrInfos.Title))
+	newFilename := am.expectedFilename(ctx, adrInfos)
 
 	if newFilename != filename {
-		from := path.Join(am.Config.Path, filename)
-		to := path.Join(am.Config.Path, newFilename)
-		logger.Printf("Renaming: %s -> %s\n", from, to)
-		err = os.Rename(from, to)
+		from := path.Join(am.AdrDirectory(), filename)
+		to := path.Join(am.AdrDirectory(), newFilename)
+		logger.Debug("Renaming ADR file", "from", from, "to", to)
+		err = utils.RenameFile(from, to)
 		if err != nil {
-			logger.Printf("Could not rename file to '%s': %v\n", newFilename, err)
-			return errors.New(fmt.Sprintf("Could not rename file to '%s': %v\n", newFilename, err))
+			logger.Debug("Could not rename ADR file", "to", newFilename, "err", err)
+			return filename, errors.New(fmt.Sprintf("Could not rename file to '%s': %v\n", newFilename, err))
 		}
 
-		logger.Printf("Updated ADR filename: %s -> %s\n", filename, newFilename)
+		logger.Debug("Updated ADR filename", "from", filename, "to", newFilename)
 	}
 
-	return nil
+	return newFilename, nil
 }
 
-func (am AdrManager) createAdrFile(adrDirectory string, filename string, content *template.Template, data data.AdrVars) {
+// Get the filename (relative to the ADR folder) which fits to the ADR's
+// category, index and title.
+func (am AdrManager) expectedFilename(ctx context.Context, adrInfos data.AdrInfo) string {
+	res := filepath.Join(adrInfos.Category, constructFilenameFromIndexAndTitle(am.createIndexForCategory(ctx, adrInfos.Category, adrInfos.Index), strings.TrimSpace(adrInfos.Title)))
+	if adrInfos.Archived {
+		res = filepath.Join(data.ArchiveFolder, res)
+	}
 
-	f, err := os.Create(filepath.Join(adrDirectory, filename))
+	return res
+}
 
+// Create the ADR file from the template, failing with an error wrapping
+// fs.ErrExist if the file already exists.
+func (am AdrManager) createAdrFile(adrDirectory string, filename string, content *template.Template, adrVars data.AdrVars) error {
+	var buf bytes.Buffer
+	err := content.Execute(&buf, adrVars)
 	if err != nil {
-		log.Fatal(err)
+		return &data.ParseError{File: contentTemplateName, Reason: err.Error()}
 	}
-	defer f.Close()
 
-	err2 := content.Execute(f, data)
+	return utils.CreateFile(filepath.Join(adrDirectory, filename), buf.Bytes(), 0644)
+}
 
-	if err2 != nil {
-		log.Fatal(err2)
+// Check if an ADR file (active or archived) with the index exists in the
+// number sequence of the category, i.e. in the folder of the category if it
+// has a sequence of its own, otherwise in all folders sharing the global one.
+func (am AdrManager) isIndexInUse(ctx context.Context, category string, index string) bool {
+	files, err := am.getSequenceFiles(ctx, category)
+	if err != nil {
+		return false
+	}
+	for _, f := range files {
+		if strings.HasPrefix(filepath.Base(f), index+"-") {
+			return true
+		}
 	}
+
+	return false
 }
 
 func createDateString() string {
@@ -348,39 +740,65 @@ func createDateString() string {
 	return currentTime.Format("2006-01-02")
 }
 
-func (am AdrManager) getNewIndexString(logger *log.Logger) string {
-	lastIndex, err := am.getLatestIndex(logger)
+func (am AdrManager) getNewIndexString(ctx context.Context, category string) string {
+	return am.createIndexForCategory(ctx, category, am.getNewIndex(ctx, category))
+}
+
+// Get the number for a new ADR in the number sequence of the given category.
+func (am AdrManager) getNewIndex(ctx context.Context, category string) int {
+	lastIndex, err := am.getLatestIndex(ctx, category)
 	if err != nil {
-		return am.createIndexByNumber(1, logger)
+		return 1
 	}
-	lastIndex = lastIndex + 1
-	return am.createIndexByNumber(lastIndex, logger)
+	return lastIndex + 1
 }
 
-func (am AdrManager) getLatestIndex(logger *log.Logger) (int, error) {
-	files, err := am.GetAllAdrFileNames(logger)
-
+// Get the highest index used in the number sequence of the given category.
+func (am AdrManager) getLatestIndex(ctx context.Context, category string) (int, error) {
+	logger := utils.Logger(ctx)
+	files, err := am.getSequenceFiles(ctx, category)
 	if err != nil {
-		logger.Printf("Error when trying to load existing ADR files: %v\n", err)
 		return 0, err
 	}
 
 	if len(files) == 0 {
-		logger.Println("Found no ADR files.")
+		logger.Debug("Found no ADR files")
 		return 0, errors.New("No ADR files found.")
 	}
 
-	return am.getMaxIndex(files, logger), nil
+	return am.getMaxIndex(ctx, files), nil
+}
+
+// Get the ADR files (active and archived, relative to the ADR folder) in the
+// number sequence of the given category.
+func (am AdrManager) getSequenceFiles(ctx context.Context, category string) ([]string, error) {
+	logger := utils.Logger(ctx)
+	allFiles, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		logger.Debug("Error when trying to load existing ADR files", "err", err)
+		return nil, err
+	}
+
+	_, _, ownSequence := am.Config.NumberingOfCategory(category)
+	files := make([]string, 0)
+	for _, f := range allFiles {
+		fileCategory := data.CategoryOfAdrFile(f)
+		_, _, fileOwnSequence := am.Config.NumberingOfCategory(fileCategory)
+		if (ownSequence && fileCategory == category) || (!ownSequence && !fileOwnSequence) {
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
 }
 
-func (am AdrManager) getMaxIndex(filenames []string, logger *log.Logger) int {
+func (am AdrManager) getMaxIndex(ctx context.Context, filenames []string) int {
+	logger := utils.Logger(ctx)
 	maxNumber := 0
 
 	for _, file := range filenames {
-		logger.Printf("Trying to extract index from file of name '%s'\n", file)
-		indexPart := file[:am.Config.Digits]
-		logger.Printf("Found index parts: %s\n", indexPart)
-		index, err := strconv.Atoi(indexPart)
+		logger.Debug("Trying to extract index from file name", "file", file)
+		index, err := am.ExtractAdrIndexFromFile(file)
 
 		if err == nil && index > maxNumber {
 			maxNumber = index
@@ -390,10 +808,14 @@ func (am AdrManager) getMaxIndex(filenames []string, logger *log.Logger) int {
 	return maxNumber
 }
 
-func (am AdrManager) createIndexByNumber(number int, logger *log.Logger) string {
+// Create the index string for an ADR of the given category, with the
+// prefix and number of digits of the category's number sequence.
+func (am AdrManager) createIndexForCategory(ctx context.Context, category string, number int) string {
+	logger := utils.Logger(ctx)
+	prefix, digits, _ := am.Config.NumberingOfCategory(category)
 	s := fmt.Sprintf("%020d", number)
-	logger.Printf("Trying to create index by number: %s", s)
-	return am.Config.Prefix + s[len(s)-am.Config.Digits:]
+	logger.Debug("Trying to create index by number", "index", s)
+	return prefix + s[len(s)-digits:]
 }
 
 func generateBaseFileName(title string) string {
@@ -0,0 +1,144 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dukemarty/adr-go/data"
+)
+
+// Create an AdrManager for an empty ADR log in a temporary directory.
+func newTestAdrManager(t *testing.T) *AdrManager {
+	t.Helper()
+	config := data.NewConfiguration("en", "docs/adr/", "", 4, "template-short.md")
+	am := NewAdrManager(*config)
+	am.BaseDir = t.TempDir()
+	if err := os.MkdirAll(am.AdrDirectory(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return am
+}
+
+func TestAddAdrWithContentConcurrently(t *testing.T) {
+	const n = 20
+	am := newTestAdrManager(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	files := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			files[i], errs[i] = am.AddAdrWithContent(ctx, "Concurrent decision", defaultTemplate)
+		}(i)
+	}
+	wg.Wait()
+
+	distinct := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("AddAdrWithContent failed: %v", errs[i])
+		}
+		distinct[files[i]] = true
+	}
+	if len(distinct) != n {
+		t.Errorf("expected %d distinct ADR files, got %d: %v", n, len(distinct), files)
+	}
+
+	adrFiles, err := am.GetAllAdrFileNames(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adrFiles) != n {
+		t.Errorf("expected %d ADR files in the ADR folder, got %d", n, len(adrFiles))
+	}
+	numbers := make(map[string]bool)
+	for _, f := range adrFiles {
+		numbers[strings.SplitN(filepath.Base(f), "-", 2)[0]] = true
+	}
+	if len(numbers) != n {
+		t.Errorf("expected %d distinct ADR numbers, got %d: %v", n, len(numbers), adrFiles)
+	}
+
+	toc, err := os.ReadFile(filepath.Join(am.AdrDirectory(), "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := 0
+	for _, line := range strings.Split(string(toc), "\n") {
+		if strings.HasPrefix(line, "* [") {
+			entries++
+		}
+	}
+	if entries != n {
+		t.Errorf("expected %d TOC entries, got %d:\n%s", n, entries, toc)
+	}
+}
+
+func TestGetAdrFilenamesFilteredRejectsInvalidKeyword(t *testing.T) {
+	am := newTestAdrManager(t)
+	ctx := context.Background()
+	if _, err := am.AddAdrWithContent(ctx, "Use Kafka", defaultTemplate); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := am.GetAdrFilenamesFiltered(ctx, []string{"kafka"}, false)
+	if err != nil || len(files) != 1 {
+		t.Errorf("expected one match for a valid keyword, got %v, %v", files, err)
+	}
+
+	var invalidKeyword *data.InvalidKeywordError
+	files, err = am.GetAdrFilenamesFiltered(ctx, []string{"kafka", "foo("}, false)
+	if !errors.As(err, &invalidKeyword) || invalidKeyword.Keyword != "foo(" {
+		t.Errorf("expected an InvalidKeywordError for 'foo(', got %v (matches %v)", err, files)
+	}
+}
+
+func TestAddAdrFromMalformedTemplate(t *testing.T) {
+	am := newTestAdrManager(t)
+	ctx := context.Background()
+	templateFile := filepath.Join(am.AdrDirectory(), "template-broken.md")
+	if err := os.WriteFile(templateFile, []byte("# {{.NUMBER}. {{.TITLE}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var parseError *data.ParseError
+	_, err := am.AddAdrFromTemplate(ctx, "Use Kafka", "", "template-broken.md")
+	if !errors.As(err, &parseError) || parseError.File != "template-broken.md" {
+		t.Errorf("expected a ParseError of the template, got %v", err)
+	}
+	if files, _ := am.GetAllAdrFileNames(ctx); len(files) > 0 {
+		t.Errorf("expected no ADR to be created, got %v", files)
+	}
+}
+
+// A number is in use if an ADR of another folder sharing the number
+// sequence has it, but not if only a category with a sequence of its own
+// has it.
+func TestIsIndexInUseChecksWholeSequence(t *testing.T) {
+	am := newTestAdrManager(t)
+	am.Config.Categories = map[string]data.CategoryConfiguration{"security": {Prefix: "SEC-", Digits: 3}}
+	ctx := context.Background()
+	writeTestAdrs(t, am, map[string]string{
+		"api/0002-use-rest.md":        "# 0002. Use REST\n",
+		"security/SEC-003-use-tls.md": "# SEC-003. Use TLS\n",
+	})
+
+	if !am.isIndexInUse(ctx, "", "0002") {
+		t.Errorf("expected 0002 of category api to be in use in the shared sequence")
+	}
+	if am.isIndexInUse(ctx, "", "0003") {
+		t.Errorf("expected 0003 not to be in use in the shared sequence")
+	}
+	if !am.isIndexInUse(ctx, "security", "SEC-003") {
+		t.Errorf("expected SEC-003 to be in use in the sequence of category security")
+	}
+}
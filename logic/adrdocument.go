@@ -0,0 +1,380 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+	"golang.org/x/exp/slices"
+)
+
+// Schema (name and version) of documents containing the complete ADRs, as
+// written by the full exports and read by import. It gets a new version
+// whenever a field is removed or changes its meaning.
+const AdrCollectionSchema = "adr-go/adrs/v1"
+
+// The complete content of a set of ADRs, as written by the full exports
+// and read by import.
+type AdrCollection struct {
+	Schema string `json:"schema" yaml:"schema"`
+	// Configuration of the ADR log the ADRs stem from; not set if they stem
+	// from several ADR logs or projects.
+	Configuration *data.Configuration `json:"configuration,omitempty" yaml:"configuration,omitempty"`
+	Adrs          []AdrDocument       `json:"adrs" yaml:"adrs"`
+}
+
+// The complete content of an ADR.
+type AdrDocument struct {
+	// Name of the project or ADR log the ADR belongs to, if loaded as part
+	// of a combined view.
+	Origin   string `json:"origin,omitempty" yaml:"origin,omitempty"`
+	Id       string `json:"id" yaml:"id"`
+	Index    int    `json:"index" yaml:"index"`
+	Category string `json:"category,omitempty" yaml:"category,omitempty"`
+	Title    string `json:"title" yaml:"title"`
+	// Filename relative to the ADR folder, with '/' as separator.
+	Filename string `json:"filename" yaml:"filename"`
+	// Path of the ADR file, including the ADR folder.
+	Path     string `json:"path" yaml:"path"`
+	Archived bool   `json:"archived" yaml:"archived"`
+	// All status entries, oldest first.
+	Status []data.StatusChange `json:"status" yaml:"status"`
+	// Lines like 'Date: 2023-06-30' between the title and the first section.
+	Metadata map[string]string `json:"metadata" yaml:"metadata"`
+	// The sections of the ADR, i.e. the parts started by a level 2 heading
+	// like '## Context'.
+	Sections []AdrSection `json:"sections" yaml:"sections"`
+	Links    []AdrLink    `json:"links" yaml:"links"`
+	// The complete ADR file.
+	Markdown string `json:"markdown" yaml:"markdown"`
+}
+
+type AdrSection struct {
+	Heading  string `json:"heading" yaml:"heading"`
+	Markdown string `json:"markdown" yaml:"markdown"`
+	Text     string `json:"text" yaml:"text"`
+}
+
+type AdrLink struct {
+	Text   string `json:"text" yaml:"text"`
+	Target string `json:"target" yaml:"target"`
+	// Line of the link in the ADR file (starting at 1).
+	Line int `json:"line" yaml:"line"`
+	// Id of the linked ADR, if the link points to one of the ADRs of the
+	// collection.
+	Adr string `json:"adr,omitempty" yaml:"adr,omitempty"`
+}
+
+// Regex for metadata lines like 'Date: 2023-06-30'.
+var metadataRegex = regexp.MustCompile(`^([^\s:][^:]*):\s+(.*)$`)
+
+// Load the complete content of the ADRs. If all of them belong to the
+// current ADR log (i.e. have no origin), the log's configuration is added.
+// ADRs which can not be read are skipped.
+func LoadAdrCollection(ctx context.Context, entries []AdrStatus) AdrCollection {
+	logger := utils.Logger(ctx)
+	res := AdrCollection{Schema: AdrCollectionSchema, Adrs: make([]AdrDocument, 0, len(entries))}
+
+	singleLog := true
+	idsByPath := make(map[string]string)
+	for _, e := range entries {
+		singleLog = singleLog && len(e.Origin) == 0
+		idsByPath[path.Clean(filepath.ToSlash(e.Path))] = e.Id
+	}
+	if singleLog {
+		if am, err := OpenAdrManager(ctx); err == nil {
+			res.Configuration = &am.Config
+		}
+	}
+
+	for _, e := range entries {
+		doc, err := loadAdrDocument(ctx, e, idsByPath)
+		if err != nil {
+			logger.Warn("Could not load ADR", "file", e.Path, "err", err)
+			continue
+		}
+		res.Adrs = append(res.Adrs, doc)
+	}
+
+	return res
+}
+
+// Load the complete content of the ADR; links pointing to a file of
+// idsByPath (paths with '/' as separator) get the id of that ADR.
+func loadAdrDocument(ctx context.Context, e AdrStatus, idsByPath map[string]string) (AdrDocument, error) {
+	res := AdrDocument{
+		Origin:   e.Origin,
+		Id:       e.Id,
+		Index:    e.Index,
+		Category: e.Category,
+		Title:    e.Title,
+		Filename: filepath.ToSlash(e.Filename),
+		Path:     filepath.ToSlash(e.Path),
+		Archived: e.Archived,
+		Sections: make([]AdrSection, 0),
+		Links:    make([]AdrLink, 0),
+	}
+
+	content, err := utils.ReadFile(e.Path)
+	if err != nil {
+		return res, err
+	}
+	res.Markdown = string(content)
+	res.Status, err = data.ReadStatusEntries(ctx, e.Path)
+	if err != nil {
+		return res, err
+	}
+
+	doc, err := utils.ParseMarkdown(content)
+	if err != nil {
+		return res, &data.ParseError{File: e.Path, Reason: err.Error()}
+	}
+	preamble, sections := doc.SplitSections(2)
+	res.Metadata = metadataOfPreamble(preamble.Markdown)
+	for _, s := range sections {
+		res.Sections = append(res.Sections, AdrSection{Heading: s.Heading, Markdown: s.Markdown, Text: s.Text})
+	}
+
+	dir := path.Dir(res.Path)
+	for _, l := range doc.FindLinks() {
+		link := AdrLink{Text: l.Text, Target: l.Destination, Line: l.Line}
+		target := strings.SplitN(l.Destination, "#", 2)[0]
+		if len(target) > 0 && !strings.Contains(target, ":") && !path.IsAbs(target) {
+			link.Adr = idsByPath[path.Join(dir, target)]
+		}
+		res.Links = append(res.Links, link)
+	}
+
+	return res, nil
+}
+
+// Get the metadata from the lines like 'Date: 2023-06-30' of the text
+// between the title and the first section of an ADR.
+func metadataOfPreamble(preamble string) map[string]string {
+	res := make(map[string]string)
+	for _, line := range strings.Split(preamble, "\n") {
+		if match := metadataRegex.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			res[strings.TrimSpace(match[1])] = strings.TrimSpace(match[2])
+		}
+	}
+
+	return res
+}
+
+// Read a document with the complete content of ADRs, as written by the full
+// JSON export.
+func ReadAdrCollection(content []byte, source string) (AdrCollection, error) {
+	var res AdrCollection
+	err := json.Unmarshal(content, &res)
+	if err != nil {
+		return res, &data.ParseError{File: source, Reason: err.Error()}
+	}
+	if res.Schema != AdrCollectionSchema {
+		return res, &data.ParseError{File: source, Reason: fmt.Sprintf("unsupported schema '%s', must be '%s'", res.Schema, AdrCollectionSchema)}
+	}
+
+	return res, nil
+}
+
+// Get the content of the ADR file: the complete markdown if given,
+// otherwise it is assembled from title, metadata, status entries (unless
+// there is a 'Status' section) and sections.
+func (am AdrManager) adrDocumentContent(ctx context.Context, doc AdrDocument) string {
+	if len(doc.Markdown) > 0 {
+		return doc.Markdown
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s. %s\n\n", am.adrDocumentId(ctx, doc), doc.Title))
+
+	keys := make([]string, 0, len(doc.Metadata))
+	for k := range doc.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("%s: %s\n", k, doc.Metadata[k]))
+	}
+	if len(keys) > 0 {
+		sb.WriteString("\n")
+	}
+
+	hasStatusSection := false
+	for _, s := range doc.Sections {
+		hasStatusSection = hasStatusSection || strings.EqualFold(s.Heading, "Status")
+	}
+	if !hasStatusSection && len(doc.Status) > 0 {
+		sb.WriteString("## Status\n\n")
+		for _, s := range doc.Status {
+			sb.WriteString(fmt.Sprintf("%s %s\n\n", s.Date, s.Status))
+		}
+	}
+	for _, s := range doc.Sections {
+		sb.WriteString("## " + s.Heading + "\n\n")
+		if markdown := strings.TrimSpace(s.Markdown); len(markdown) > 0 {
+			sb.WriteString(markdown + "\n\n")
+		}
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Get the id of an imported ADR: the given one, or one created from its
+// index and category.
+func (am AdrManager) adrDocumentId(ctx context.Context, doc AdrDocument) string {
+	if len(doc.Id) > 0 {
+		return doc.Id
+	}
+
+	return am.createIndexForCategory(ctx, doc.Category, doc.Index)
+}
+
+// Get the filename of an imported ADR relative to the ADR folder: the given
+// one, or one created from its id, title, category and archived flag.
+func (am AdrManager) adrDocumentFilename(ctx context.Context, doc AdrDocument) (string, error) {
+	filename := doc.Filename
+	if len(filename) == 0 {
+		if len(doc.Title) == 0 {
+			return "", errors.New(fmt.Sprintf("ADR %s has neither filename nor title", am.adrDocumentId(ctx, doc)))
+		}
+		filename = path.Join(doc.Category, constructFilenameFromIndexAndTitle(am.adrDocumentId(ctx, doc), doc.Title))
+		if doc.Archived {
+			filename = path.Join(data.ArchiveFolder, filename)
+		}
+	}
+	filename = path.Clean(filepath.ToSlash(filename))
+	if strings.HasPrefix(filename, "../") || path.IsAbs(filename) || !am.isAdrFileName(path.Base(filename)) {
+		return "", errors.New(fmt.Sprintf("Invalid filename '%s' of ADR %s, must be an ADR file in the ADR folder", doc.Filename, am.adrDocumentId(ctx, doc)))
+	}
+
+	return filepath.FromSlash(filename), nil
+}
+
+// Import the ADRs of the collection into the ADR log, each into the file
+// named by its filename (relative to the ADR folder), and regenerate the
+// TOC. Existing ADR files are only overwritten if overwrite is set.
+//
+// Returns the paths of the written files, including the TOC.
+func (am AdrManager) ImportAdrs(ctx context.Context, collection AdrCollection, overwrite bool) ([]string, error) {
+	logger := utils.Logger(ctx)
+
+	// check all ADRs before writing any of them
+	files := make([]string, 0, len(collection.Adrs))
+	for _, doc := range collection.Adrs {
+		filename, err := am.adrDocumentFilename(ctx, doc)
+		if err != nil {
+			return nil, err
+		}
+		adrPath := filepath.Join(am.AdrDirectory(), filename)
+		if !overwrite && utils.FileExists(adrPath) {
+			return nil, errors.New(fmt.Sprintf("ADR file '%s' exists already", adrPath))
+		}
+		files = append(files, adrPath)
+	}
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for i, doc := range collection.Adrs {
+		adrPath := files[i]
+		err := utils.MkdirAll(filepath.Dir(adrPath))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Could not create folder for ADR file '%s': %v", adrPath, err))
+		}
+		content := []byte(am.adrDocumentContent(ctx, doc))
+		if overwrite {
+			err = utils.WriteFile(adrPath, content, 0644)
+		} else {
+			err = utils.CreateFile(adrPath, content, 0644)
+		}
+		if errors.Is(err, fs.ErrExist) {
+			return nil, errors.New(fmt.Sprintf("ADR file '%s' exists already", adrPath))
+		} else if err != nil {
+			return nil, errors.New(fmt.Sprintf("Could not write ADR file '%s': %v", adrPath, err))
+		}
+		logger.Debug("Imported ADR", "file", adrPath)
+	}
+
+	readmePath := filepath.Join(am.AdrDirectory(), "README.md")
+	err = utils.WriteFile(readmePath, []byte(am.GenerateToc(ctx)), 0644)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not write TOC '%s': %v", readmePath, err))
+	}
+
+	return append(files, readmePath), nil
+}
+
+// Import the ADRs of the collection into the selected ADR log, see
+// AdrManager.ImportAdrs. If no ADR log is initialized in the current
+// directory, one is initialized with the collection's configuration (or the
+// default configuration, if the collection has none).
+//
+// Returns the paths of the written files, including the configuration and
+// templates of a new ADR log.
+func ImportAdrCollection(ctx context.Context, collection AdrCollection, overwrite bool) ([]string, error) {
+	logger := utils.Logger(ctx)
+	files := make([]string, 0)
+
+	am, err := OpenAdrManager(ctx)
+	var notInitialized *data.NotInitializedError
+	if errors.As(err, &notInitialized) && len(selectedLogDir) == 0 {
+		config := data.NewConfiguration("en", "docs/adr/", "", 4, "template-short.md")
+		if collection.Configuration != nil {
+			if err := checkImportedConfiguration(*collection.Configuration); err != nil {
+				return nil, err
+			}
+			config = collection.Configuration
+		}
+		logger.Info("Initializing ADR log for import", "path", config.Path)
+		newAm := NewAdrManager(*config)
+		utils.StartJournal(newAm.DataDirectory())
+		err = newAm.Init(ctx)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, configFileName, filepath.Join(config.Path, "template-short.md"), filepath.Join(config.Path, "template-long.md"))
+		// keeps the journal started above, so that the initialization and
+		// the import are undone together
+		am, err = OpenAdrManagerAt(ctx, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := am.ImportAdrs(ctx, collection, overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(files, imported...), nil
+}
+
+// Check the folders of a configuration from an imported document, before a
+// new ADR log is initialized with it: like the filenames of the ADRs, they
+// must be relative and must not leave the project directory.
+func checkImportedConfiguration(config data.Configuration) error {
+	folders := append([]string{config.Path, config.ExporterTemplates}, config.DocFolders...)
+	for _, folder := range folders {
+		slashed := filepath.ToSlash(folder)
+		if path.IsAbs(slashed) || filepath.IsAbs(folder) || len(filepath.VolumeName(folder)) > 0 || slices.Contains(strings.Split(slashed, "/"), "..") {
+			return errors.New(fmt.Sprintf("Invalid folder '%s' in the configuration of the document, must be relative and must not contain '..'", folder))
+		}
+	}
+
+	return nil
+}
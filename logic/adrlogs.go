@@ -0,0 +1,259 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// An AdrLog is one ADR project (a config file with its ADR folder) inside
+// a repository which may contain several of them, e.g. one per service.
+type AdrLog struct {
+	Name string
+	// Directory containing the config file, relative to the working directory.
+	BaseDir string
+}
+
+// Directory of the ADR log selected with SelectAdrLog; used by OpenAdrManager.
+var selectedLogDir = ""
+
+// Reference to an ADR in another (or the same) ADR log, e.g. "billing:0004".
+var crossLogRefRegex = regexp.MustCompile(`^([A-Za-z][\w.-]*):(\d+)$`)
+
+// Markdown link whose target is either a cross-log reference, or which has
+// a cross-log reference as title, e.g. [x](billing:0004) or [x](../a.md "billing:0004").
+var crossLogLinkRegex = regexp.MustCompile(`\]\(([^)\s]+)(?:\s+"([A-Za-z][\w.-]*:\d+)")?\)`)
+
+// Find the root directory of the repository containing the working
+// directory, i.e. the closest directory containing a '.git' entry. If
+// there is none, the working directory is returned.
+func FindRepositoryRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	dir := cwd
+	for {
+		if utils.FileExists(filepath.Join(dir, ".git")) {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return cwd, nil
+		}
+		dir = parent
+	}
+}
+
+// Discover all ADR logs below the directory root. Hidden directories as
+// well as 'node_modules' and 'vendor' folders are not searched.
+//
+// Returns the found ADR logs, with their base directories relative to
+// the working directory.
+func DiscoverAdrLogs(ctx context.Context, root string) ([]AdrLog, error) {
+	logger := utils.Logger(ctx)
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]AdrLog, 0)
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger.Warn("Could not access path during ADR log discovery", "path", p, "err", err)
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules" || d.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+
+		config, err := data.LoadConfigurationFrom(p)
+		if err != nil {
+			return nil
+		}
+		baseDir, err := filepath.Rel(cwd, p)
+		if err != nil {
+			baseDir = p
+		}
+		name := config.Name
+		if len(name) == 0 {
+			abs, _ := filepath.Abs(p)
+			name = filepath.Base(abs)
+		}
+		logger.Debug("Discovered ADR log", "name", name, "baseDir", baseDir)
+		res = append(res, AdrLog{Name: name, BaseDir: baseDir})
+
+		return nil
+	})
+
+	return res, err
+}
+
+// Discover all ADR logs of the repository containing the working directory.
+func DiscoverAdrLogsOfRepository(ctx context.Context) ([]AdrLog, error) {
+	logger := utils.Logger(ctx)
+	root, err := FindRepositoryRoot()
+	if err != nil {
+		logger.Debug("Could not determine repository root", "err", err)
+		return nil, err
+	}
+
+	return DiscoverAdrLogs(ctx, root)
+}
+
+// Find the ADR log with the given name in the repository containing the
+// working directory.
+func FindAdrLog(ctx context.Context, name string) (AdrLog, error) {
+	logs, err := DiscoverAdrLogsOfRepository(ctx)
+	if err != nil {
+		return AdrLog{}, err
+	}
+
+	for _, l := range logs {
+		if l.Name == name {
+			return l, nil
+		}
+	}
+
+	return AdrLog{}, errors.New(fmt.Sprintf("Could not find ADR log '%s'", name))
+}
+
+// Select the ADR log with the given name as the one used by all following
+// operations (via OpenAdrManager), instead of the one in the working directory.
+func SelectAdrLog(ctx context.Context, name string) error {
+	logger := utils.Logger(ctx)
+	l, err := FindAdrLog(ctx, name)
+	if err != nil {
+		logger.Debug("Could not select ADR log", "err", err)
+		return err
+	}
+
+	logger.Debug("Selected ADR log", "name", l.Name, "baseDir", l.BaseDir)
+	selectedLogDir = l.BaseDir
+
+	return nil
+}
+
+// Get the status of all ADRs of all ADR logs in the repository. Each entry's
+// Origin is set to the name of the log.
+func GetListOfAllAdrsStatusOfAllLogs(ctx context.Context) ([]AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	logs, err := DiscoverAdrLogsOfRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]AdrStatus, 0)
+	for _, l := range logs {
+		am, err := OpenAdrManagerAt(ctx, l.BaseDir)
+		if err != nil {
+			logger.Debug("Skipping ADR log", "name", l.Name, "err", err)
+			continue
+		}
+		statuss, err := am.GetListOfAllAdrsStatus(ctx)
+		if err != nil {
+			logger.Debug("Skipping ADR log, could not load ADR status", "name", l.Name, "err", err)
+			continue
+		}
+		res = append(res, withOrigin(statuss, l.Name)...)
+	}
+
+	return res, nil
+}
+
+// Resolve a cross-log reference like "billing:0004" to the referenced ADR.
+//
+// Returns the info of the ADR (whose RelativePath is relative to the
+// working directory), or an error if the log or the ADR does not exist.
+func ResolveCrossLogReference(ctx context.Context, ref string) (data.AdrInfo, error) {
+	parts := crossLogRefRegex.FindStringSubmatch(ref)
+	if parts == nil {
+		return data.AdrInfo{}, errors.New(fmt.Sprintf("'%s' is not a valid ADR reference", ref))
+	}
+	index, _ := strconv.Atoi(parts[2])
+
+	l, err := FindAdrLog(ctx, parts[1])
+	if err != nil {
+		return data.AdrInfo{}, err
+	}
+	am, err := OpenAdrManagerAt(ctx, l.BaseDir)
+	if err != nil {
+		return data.AdrInfo{}, err
+	}
+	filename, err := am.GetAdrFilenameByIndex(ctx, index)
+	if err != nil {
+		return data.AdrInfo{}, err
+	}
+
+	return data.LoadAdrInfo(ctx, am.AdrDirectory(), filename)
+}
+
+// Find all cross-log references in the links of a markdown text.
+func FindCrossLogReferences(content string) []string {
+	res := make([]string, 0)
+	for _, m := range crossLogLinkRegex.FindAllStringSubmatch(content, -1) {
+		if len(m[2]) > 0 {
+			res = append(res, m[2])
+		} else if crossLogRefRegex.MatchString(m[1]) {
+			res = append(res, m[1])
+		}
+	}
+
+	return res
+}
+
+// Rewrite all links with cross-log references in the markdown text content,
+// so that they point to the current file of the referenced ADR. The
+// reference is kept as link title, so that the link can be updated again
+// if the referenced ADR is renamed. fromDir is the directory of the file
+// containing content, used to create relative links.
+//
+// References which can not be resolved are left unchanged.
+func ResolveCrossLogLinks(ctx context.Context, content string, fromDir string) string {
+	logger := utils.Logger(ctx)
+	return crossLogLinkRegex.ReplaceAllStringFunc(content, func(link string) string {
+		m := crossLogLinkRegex.FindStringSubmatch(link)
+		ref := m[2]
+		if len(ref) == 0 {
+			ref = m[1]
+		}
+		if !crossLogRefRegex.MatchString(ref) {
+			return link
+		}
+
+		info, err := ResolveCrossLogReference(ctx, ref)
+		if err != nil {
+			logger.Warn("Could not resolve ADR reference", "ref", ref, "err", err)
+			return link
+		}
+
+		return fmt.Sprintf(`](%s "%s")`, relativeLink(fromDir, info.RelativePath), ref)
+	})
+}
+
+// Create a relative link (with forward slashes) from directory fromDir to
+// the file target; both are relative to the working directory.
+func relativeLink(fromDir string, target string) string {
+	rel, err := filepath.Rel(fromDir, target)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+
+	return filepath.ToSlash(rel)
+}
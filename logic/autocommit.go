@@ -0,0 +1,134 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Verbs used in commit messages for status changes, by status in upper case.
+var statusCommitVerbs = map[string]string{
+	"PROPOSED":   "propose",
+	"ACCEPTED":   "accept",
+	"DONE":       "complete",
+	"DEPRECATED": "deprecate",
+	"SUPERSEDED": "supersede",
+	"WITHDRAWN":  "withdraw",
+}
+
+// Stage the given files (paths relative to the working directory) and
+// commit them, and only them, with the given message. Files which were
+// renamed away or deleted are committed as deletion.
+func CommitFiles(ctx context.Context, message string, files []string) error {
+	logger := utils.Logger(ctx)
+	if !utils.IsGitRepository() {
+		return errors.New("Not inside a git repository, changes can not be committed.")
+	}
+
+	paths := make([]string, 0)
+	for _, f := range files {
+		if utils.FileExists(f) {
+			paths = append(paths, f)
+		} else if _, err := utils.RunGit("ls-files", "--error-unmatch", "--", f); err == nil {
+			paths = append(paths, f)
+		} else {
+			logger.Debug("Skipping file for commit, neither existing nor tracked", "file", f)
+		}
+	}
+	if len(paths) == 0 {
+		return errors.New("No files to commit.")
+	}
+	if utils.IsDryRun() {
+		logger.Debug("Dry run, not committing", "paths", paths)
+		return nil
+	}
+
+	_, err := utils.RunGit(append([]string{"add", "-A", "--"}, paths...)...)
+	if err != nil {
+		logger.Debug("Could not stage files", "err", err)
+		return err
+	}
+	_, err = utils.RunGit(append([]string{"commit", "-q", "-m", message, "--"}, paths...)...)
+	if err != nil {
+		logger.Debug("Could not commit files", "err", err)
+		return err
+	}
+	logger.Debug("Committed files", "paths", paths, "message", message)
+
+	return nil
+}
+
+// Create and check out a branch for the proposal of a new ADR, named
+// after the ADR file, e.g. 'adr/0012-use-kafka'. Uncommitted changes
+// are carried over to the new branch.
+// Returns the name of the branch.
+func CreateProposalBranch(ctx context.Context, adrFile string) (string, error) {
+	logger := utils.Logger(ctx)
+	if !utils.IsGitRepository() {
+		return "", errors.New("Not inside a git repository, no branch can be created.")
+	}
+
+	branch := "adr/" + strings.TrimSuffix(filepath.Base(adrFile), filepath.Ext(adrFile))
+	if utils.IsDryRun() {
+		return branch, nil
+	}
+	_, err := utils.RunGit("checkout", "-q", "-b", branch)
+	if err != nil {
+		logger.Debug("Could not create branch", "branch", branch, "err", err)
+		return "", err
+	}
+
+	return branch, nil
+}
+
+// Create the commit message for a new ADR, e.g. "docs(adr): propose 0012 Use Kafka".
+func CommitMessageForNewAdr(ctx context.Context, adrFile string) string {
+	return commitMessageForAdr(ctx, "propose", adrFile)
+}
+
+// Create the commit message for the status change of an ADR, e.g.
+// "docs(adr): accept 0012 Use Kafka".
+func CommitMessageForStatusChange(ctx context.Context, adrFile string, newStatus string) string {
+	verb, ok := statusCommitVerbs[strings.ToUpper(newStatus)]
+	if !ok {
+		verb = "set status " + strings.ToLower(newStatus) + " of"
+	}
+
+	return commitMessageForAdr(ctx, verb, adrFile)
+}
+
+// Create the commit message for an update of the ADR repository.
+func CommitMessageForUpdate(result UpdateResult) string {
+	if len(result.Renames) == 0 {
+		return "docs(adr): update TOC"
+	}
+
+	return fmt.Sprintf("docs(adr): update TOC and rename %d ADR(s)", len(result.Renames))
+}
+
+// Get the path of the README (TOC) file of the ADR repository.
+func GetTocFilePath(ctx context.Context) (string, error) {
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(am.AdrDirectory(), "README.md"), nil
+}
+
+func commitMessageForAdr(ctx context.Context, verb string, adrFile string) string {
+	info, err := data.LoadAdrInfo(ctx, "", adrFile)
+	if err != nil {
+		return fmt.Sprintf("docs(adr): %s %s", verb, filepath.Base(adrFile))
+	}
+
+	return fmt.Sprintf("docs(adr): %s %s %s", verb, info.Id, info.Title)
+}
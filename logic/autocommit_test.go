@@ -0,0 +1,189 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Change into a new git repository with an initialized ADR log, whose
+// initial state is committed; the working directory is restored at the
+// end of the test.
+func setupGitAdrLog(t *testing.T) *AdrManager {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		utils.StopJournal()
+		os.Chdir(wd)
+	})
+
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if _, err := utils.RunGit(args...); err != nil {
+			t.Skipf("git not usable: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	am := NewAdrManager(*data.NewConfiguration("en", "docs/adr/", "", 4, "template-short.md"))
+	if err := am.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mustRunGit(t, "add", "-A")
+	mustRunGit(t, "commit", "-q", "-m", "init")
+
+	return am
+}
+
+func mustRunGit(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := utils.RunGit(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(out)
+}
+
+// Run the steps of 'new --branch', 'status' and 'update' with committing
+// enabled, and check the resulting branch and commits.
+func TestCommitNewStatusAndUpdate(t *testing.T) {
+	am := setupGitAdrLog(t)
+	ctx := context.Background()
+
+	// new
+	adrFile, err := am.AddAdr(ctx, "Use Kafka", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, err := CreateProposalBranch(ctx, adrFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch != "adr/0001-use-kafka" {
+		t.Errorf("unexpected proposal branch '%s'", branch)
+	}
+	tocFile, err := GetTocFilePath(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CommitFiles(ctx, CommitMessageForNewAdr(ctx, adrFile), []string{adrFile, tocFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	// status
+	if err := data.AddStatusEntry(ctx, adrFile, "ACCEPTED"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CommitFiles(ctx, CommitMessageForStatusChange(ctx, adrFile, "ACCEPTED"), []string{adrFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	// update, after the title was changed in the ADR
+	content, err := os.ReadFile(adrFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(adrFile, []byte(strings.Replace(string(content), "Use Kafka", "Use Pulsar", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := UpdateAdrRepository(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := result.Changed
+	for _, r := range result.Renames {
+		files = append(files, r.From)
+	}
+	if err := CommitFiles(ctx, CommitMessageForUpdate(result), files); err != nil {
+		t.Fatal(err)
+	}
+
+	if current := mustRunGit(t, "rev-parse", "--abbrev-ref", "HEAD"); current != branch {
+		t.Errorf("expected branch '%s' to be checked out, got '%s'", branch, current)
+	}
+	expected := []string{
+		"docs(adr): update TOC and rename 1 ADR(s)",
+		"docs(adr): accept 0001 Use Kafka",
+		"docs(adr): propose 0001 Use Kafka",
+		"init",
+	}
+	messages := strings.Split(mustRunGit(t, "log", "--format=%s"), "\n")
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected commits:\n%s\nexpected:\n%s", strings.Join(messages, "\n"), strings.Join(expected, "\n"))
+	}
+	if onMain := mustRunGit(t, "log", "--format=%s", "main"); onMain != "init" {
+		t.Errorf("expected no new commits on main, got:\n%s", onMain)
+	}
+
+	committed := mustRunGit(t, "ls-tree", "-r", "--name-only", "HEAD", "--", "docs/adr")
+	if !strings.Contains(committed, "docs/adr/0001-use-pulsar.md") || strings.Contains(committed, "0001-use-kafka.md") {
+		t.Errorf("renamed ADR not committed as rename:\n%s", committed)
+	}
+	if status := mustRunGit(t, "status", "--porcelain", "--", filepath.Dir(adrFile)); len(status) > 0 {
+		t.Errorf("uncommitted changes left:\n%s", status)
+	}
+}
+
+func TestCommitFilesOnlyCommitsGivenFiles(t *testing.T) {
+	setupGitAdrLog(t)
+	ctx := context.Background()
+
+	os.WriteFile("a.txt", []byte("a\n"), 0644)
+	os.WriteFile("b.txt", []byte("b\n"), 0644)
+	if err := CommitFiles(ctx, "add a", []string{"a.txt", "missing.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := mustRunGit(t, "show", "--name-only", "--format=", "HEAD"); files != "a.txt" {
+		t.Errorf("expected only a.txt to be committed, got:\n%s", files)
+	}
+	if status := mustRunGit(t, "status", "--porcelain", "--", "b.txt"); status != "?? b.txt" {
+		t.Errorf("expected b.txt to stay untracked, got '%s'", status)
+	}
+	if err := CommitFiles(ctx, "nothing", []string{"missing.txt"}); err == nil {
+		t.Error("expected an error committing no existing files")
+	}
+}
+
+func TestCommitMessageForStatusChange(t *testing.T) {
+	setupGitAdrLog(t)
+	ctx := context.Background()
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adrFile, err := am.AddAdr(ctx, "Use Kafka", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for status, expected := range map[string]string{
+		"Accepted":   "docs(adr): accept 0001 Use Kafka",
+		"superseded": "docs(adr): supersede 0001 Use Kafka",
+		"Withdrawn":  "docs(adr): withdraw 0001 Use Kafka",
+		"Rejected":   "docs(adr): set status rejected of 0001 Use Kafka",
+	} {
+		if msg := CommitMessageForStatusChange(ctx, adrFile, status); msg != expected {
+			t.Errorf("status %s: expected '%s', got '%s'", status, expected, msg)
+		}
+	}
+	if msg := CommitMessageForUpdate(UpdateResult{}); msg != "docs(adr): update TOC" {
+		t.Errorf("unexpected update message '%s'", msg)
+	}
+}
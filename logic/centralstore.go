@@ -0,0 +1,219 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Register the ADR project in the current directory (or the selected ADR
+// log) in the central ADR store configured in the user configuration.
+//
+// Takes the name under which the project is registered (if empty, the
+// configured name or the name of the project directory is used). Returns the
+// name used for registration, or an error.
+func RegisterProject(ctx context.Context, name string) (string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := data.LoadConfiguredCentralStore()
+	if err != nil {
+		logger.Debug("Error loading central ADR store", "err", err)
+		return "", err
+	}
+
+	projectPath, err := filepath.Abs(am.BaseDir)
+	if err != nil {
+		return "", err
+	}
+	if len(name) == 0 {
+		name = am.Config.Name
+	}
+	if len(name) == 0 {
+		name = filepath.Base(projectPath)
+	}
+
+	store.Register(name, projectPath)
+	err = store.Store()
+	if err != nil {
+		logger.Debug("Error writing registry of central ADR store", "err", err)
+		return "", errors.New(fmt.Sprintf("Could not write registry of central ADR store: %v", err))
+	}
+
+	return name, nil
+}
+
+// Get the list of all projects registered in the central ADR store.
+func GetRegisteredProjects(ctx context.Context) ([]data.RegisteredProject, error) {
+	logger := utils.Logger(ctx)
+	store, err := data.LoadConfiguredCentralStore()
+	if err != nil {
+		logger.Debug("Error loading central ADR store", "err", err)
+		return nil, err
+	}
+
+	return store.Projects, nil
+}
+
+// Get the status of all ADRs of all projects registered in the central
+// ADR store. Each entry's Origin is set to the project's name.
+//
+// Projects which can not be opened are skipped (and logged).
+func GetListOfAllAdrsStatusOfAllProjects(ctx context.Context) ([]AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	projects, err := GetRegisteredProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]AdrStatus, 0)
+	for _, p := range projects {
+		am, err := OpenAdrManagerAt(ctx, p.Path)
+		if err != nil {
+			logger.Debug("Skipping project", "name", p.Name, "path", p.Path, "err", err)
+			continue
+		}
+		statuss, err := am.GetListOfAllAdrsStatus(ctx)
+		if err != nil {
+			logger.Debug("Skipping project, could not load ADR status", "name", p.Name, "err", err)
+			continue
+		}
+		res = append(res, withOrigin(statuss, p.Name)...)
+	}
+
+	return res, nil
+}
+
+// Filter the ADRs of all projects registered in the central ADR store by
+// the provided keywords, and return the status of all matching ADRs.
+func GetStatusOfAdrsFilteredInAllProjects(ctx context.Context, keywords []string, caseSensitive bool) ([]AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	if _, err := compileKeywordsToRegexes(keywords, caseSensitive); err != nil {
+		logger.Debug("Error compiling regexes for keyword search", "err", err)
+		return nil, err
+	}
+	projects, err := GetRegisteredProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]AdrStatus, 0)
+	for _, p := range projects {
+		am, err := OpenAdrManagerAt(ctx, p.Path)
+		if err != nil {
+			logger.Debug("Skipping project", "name", p.Name, "path", p.Path, "err", err)
+			continue
+		}
+		foundFiles, err := am.GetAdrFilenamesFiltered(ctx, keywords, caseSensitive)
+		if err != nil {
+			logger.Warn("Skipping project, error while filtering", "name", p.Name, "err", err)
+			continue
+		}
+		statuss, err := am.GetStatusFromListOfAdrFiles(ctx, foundFiles)
+		if err != nil {
+			logger.Debug("Skipping project, could not load ADR status", "name", p.Name, "err", err)
+			continue
+		}
+		res = append(res, withOrigin(statuss, p.Name)...)
+	}
+
+	return res, nil
+}
+
+// Get the names of all templates available in the shared folder of
+// the central ADR store.
+func GetCentralStoreTemplates(ctx context.Context) ([]string, error) {
+	logger := utils.Logger(ctx)
+	store, err := data.LoadConfiguredCentralStore()
+	if err != nil {
+		logger.Debug("Error loading central ADR store", "err", err)
+		return nil, err
+	}
+
+	files, err := os.ReadDir(store.TemplatesPath())
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0)
+	for _, f := range files {
+		if !f.IsDir() && filepath.Ext(f.Name()) == ".md" {
+			res = append(res, f.Name())
+		}
+	}
+
+	return res, nil
+}
+
+// Copy a template from the shared folder of the central ADR store into
+// the ADR folder of the current project. The copy's name gets the prefix
+// "template-" (if not present yet), so that it is not taken for an ADR.
+// Returns the path of the copy.
+func PullTemplateFromCentralStore(ctx context.Context, templateName string) (string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := readTemplateFromCentralStore(ctx, templateName)
+	if err != nil {
+		return "", err
+	}
+
+	targetName := templateName
+	if !strings.HasPrefix(targetName, "template-") {
+		targetName = "template-" + targetName
+	}
+	target := filepath.Join(am.AdrDirectory(), targetName)
+	err = utils.WriteFile(target, content, 0644)
+	if err != nil {
+		logger.Debug("Could not write template", "target", target, "err", err)
+		return "", err
+	}
+
+	return target, nil
+}
+
+// Read a template from the shared folder of the central ADR store. The
+// name must be a plain file name, so that neither reading it nor pulling it
+// into the ADR folder can reach files outside of those folders.
+func readTemplateFromCentralStore(ctx context.Context, templateName string) ([]byte, error) {
+	logger := utils.Logger(ctx)
+	if len(templateName) == 0 || strings.ContainsAny(templateName, `/\`) || strings.Contains(templateName, "..") || len(filepath.VolumeName(templateName)) > 0 {
+		return nil, errors.New(fmt.Sprintf("Invalid template name '%s', must be a file name without path separators and '..'", templateName))
+	}
+	store, err := data.LoadConfiguredCentralStore()
+	if err != nil {
+		logger.Debug("No template from central ADR store available", "err", err)
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(store.TemplatesPath(), templateName))
+	if err != nil {
+		logger.Debug("Could not read template from central ADR store", "template", templateName, "err", err)
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func withOrigin(statuss []AdrStatus, origin string) []AdrStatus {
+	for i := range statuss {
+		statuss[i].Origin = origin
+	}
+
+	return statuss
+}
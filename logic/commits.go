@@ -0,0 +1,128 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Default regex for finding the implemented ADRs in a commit message: a
+// trailer line like 'ADR: 0012' or 'ADR: 0012, SEC-003'.
+const DefaultCommitPattern = `(?mi)^ADR:\s*(.+)$`
+
+// Get the regex finding implemented ADRs in commit messages: pattern if
+// given, else the configured one, else the default.
+func (am AdrManager) commitRegex(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) == 0 {
+		pattern = am.Config.CommitPattern
+	}
+	if len(pattern) == 0 {
+		pattern = DefaultCommitPattern
+	}
+
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid commit pattern '%s': %v", pattern, err))
+	}
+	if r.NumSubexp() < 1 {
+		return nil, errors.New(fmt.Sprintf("Commit pattern '%s' has no group for the ADR id", pattern))
+	}
+
+	return r, nil
+}
+
+// Scan the git log of the current branch for commits implementing ADRs, i.e.
+// commits whose message references ADRs (by default with a trailer like
+// 'ADR: 0012'). pattern overrides the configured regex if not empty.
+//
+// Returns the commits (newest first) by ADR filename (relative to the ADR
+// folder), or an error. References to unknown ADRs are logged and ignored.
+func (am AdrManager) GetImplementingCommits(ctx context.Context, pattern string) (map[string][]utils.GitCommit, error) {
+	logger := utils.Logger(ctx)
+	r, err := am.commitRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := utils.GitLog()
+	if err != nil {
+		logger.Debug("Could not read git log", "err", err)
+		return nil, err
+	}
+
+	res := make(map[string][]utils.GitCommit)
+	filenameById := make(map[string]string)
+	for _, c := range commits {
+		added := make(map[string]bool)
+		for _, m := range r.FindAllStringSubmatch(c.Subject+"\n\n"+c.Body, -1) {
+			for _, id := range strings.FieldsFunc(m[1], func(c rune) bool { return c == ',' || c == ' ' || c == '\t' }) {
+				id = strings.TrimPrefix(strings.TrimSpace(id), "#")
+				filename, known := filenameById[id]
+				if !known {
+					filename, err = am.GetAdrFilenameById(ctx, id)
+					if err != nil {
+						logger.Debug("Commit references unknown ADR", "hash", c.Hash, "id", id)
+					}
+					filenameById[id] = filename
+				}
+				if len(filename) > 0 && !added[filename] {
+					added[filename] = true
+					res[filename] = append(res[filename], c)
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// Get the commits implementing the ADR with the given id (see
+// GetImplementingCommits), newest first.
+func GetCommitsOfAdr(ctx context.Context, adrId string, pattern string) ([]utils.GitCommit, error) {
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filename, err := am.GetAdrFilenameById(ctx, adrId)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := am.GetImplementingCommits(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return commits[filename], nil
+}
+
+// Fill the Commits of the ADR status entries (of the ADR log opened by
+// OpenAdrManager) with the commits implementing them. Without git
+// repository, the entries are left unchanged.
+func AddImplementingCommits(ctx context.Context, statuss []AdrStatus) error {
+	logger := utils.Logger(ctx)
+	if !utils.IsGitRepository() {
+		logger.Debug("Not inside a git repository, no implementing commits available")
+		return nil
+	}
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return err
+	}
+	commits, err := am.GetImplementingCommits(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	for i := range statuss {
+		statuss[i].Commits = commits[statuss[i].Filename]
+	}
+
+	return nil
+}
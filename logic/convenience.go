@@ -4,153 +4,367 @@ Copyright © 2023 Martin Loesch <development@martinloesch.net>
 package logic
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"regexp"
-	"strconv"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+	"golang.org/x/exp/slices"
 )
 
 // Get path to an ADR file bye its index.
 //
-// Takes the index as parseable string and a logger object.
+// Takes the index as string; besides a plain number, the index may also
+// be an id with prefix like "SEC-001", or a category and number like
+// "security/1".
 // Returns either the path if a fitting ADR was found, or
 // an error object.
-func GetAdrFilePathByIndexString(adrIndexStr string, logger *log.Logger) (string, error) {
+func GetAdrFilePathByIndexString(ctx context.Context, adrIndexStr string) (string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		logger.Debug("Error opening ADR management", "err", err)
+		return "", err
+	}
 
-	adrIdx, err := strconv.Atoi(adrIndexStr)
+	adrFile, err := am.GetAdrFilenameById(ctx, adrIndexStr)
 	if err != nil {
-		logger.Printf("Provided ADR index '%s' must be number, could not be parsed: %v", adrIndexStr, err)
-		return "", errors.New(fmt.Sprintf("Provided ADR index '%s' must be number, could not be parsed: %v", adrIndexStr, err))
+		logger.Debug("Could not find ADR for index", "index", adrIndexStr, "err", err)
+		return "", err
 	}
 
-	return GetAdrFilePathByIndex(adrIdx, logger)
+	return filepath.Join(am.AdrDirectory(), adrFile), nil
 }
 
-func GetAdrFilePathByIndex(adrIndex int, logger *log.Logger) (string, error) {
-	am, err := OpenAdrManager(logger)
+func GetAdrFilePathByIndex(ctx context.Context, adrIndex int) (string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
 	if err != nil {
-		logger.Printf("Error opening ADR management: %v", err)
-		return "", errors.New(fmt.Sprintf("Error opening ADR management: %v", err))
+		logger.Debug("Error opening ADR management", "err", err)
+		return "", err
 	}
 
-	adrFile, err := am.GetAdrFilenameByIndex(adrIndex, logger)
+	adrFile, err := am.GetAdrFilenameByIndex(ctx, adrIndex)
 	if err != nil {
-		logger.Printf("Could not find ADR for index %d: %v", adrIndex, err)
-		return "", errors.New(fmt.Sprintf("Could not find ADR for index %d: %v", adrIndex, err))
+		logger.Debug("Could not find ADR for index", "index", adrIndex, "err", err)
+		return "", err
 	}
 
-	return filepath.Join(am.Config.Path, adrFile), nil
+	return filepath.Join(am.AdrDirectory(), adrFile), nil
 }
 
 // Get (relative) paths for all ADRs in the repository.
 //
-// Takes a logger as parameter, returns either a list of string
-// (all ADR paths) or an error.
-func GetAllAdrFilePaths(logger *log.Logger) ([]string, error) {
-	am, err := OpenAdrManager(logger)
+// Returns either a list of string (all ADR paths) or an error.
+func GetAllAdrFilePaths(ctx context.Context) ([]string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
 	if err != nil {
-		logger.Printf("Error opening ADR management: %v", err)
-		return nil, errors.New(fmt.Sprintf("Error opening ADR management: %v", err))
+		logger.Debug("Error opening ADR management", "err", err)
+		return nil, err
 	}
 
-	filenames, err := am.GetAllAdrFileNames(logger)
+	filenames, err := am.GetAllAdrFileNames(ctx)
 	if err != nil {
-		logger.Printf("Error reading all ADR filenames: %v", err)
+		logger.Debug("Error reading all ADR filenames", "err", err)
 		return nil, errors.New(fmt.Sprintf("Error reading all ADR filenames: %v", err))
 	}
 
 	res := make([]string, 0)
 	for _, f := range filenames {
-		res = append(res, filepath.Join(am.Config.Path, f))
+		res = append(res, filepath.Join(am.AdrDirectory(), f))
 	}
 
 	return res, nil
 }
 
-func GetAdrFilenamesFiltered(keywords []string, caseSensitive bool, logger *log.Logger) ([]string, error) {
-	am, err := OpenAdrManager(logger)
+func GetAdrFilenamesFiltered(ctx context.Context, keywords []string, caseSensitive bool) ([]string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
 	if err != nil {
-		logger.Printf("Error opening ADR management: %v", err)
-		return nil, errors.New(fmt.Sprintf("Error opening ADR management: %v", err))
+		logger.Debug("Error opening ADR management", "err", err)
+		return nil, err
 	}
 
-	regexes, err := compileKeywordsToRegexes(keywords, caseSensitive)
+	return am.GetAdrFilenamesFiltered(ctx, keywords, caseSensitive)
+}
+
+func GetStatusFromListOfAdrFiles(ctx context.Context, files []string) ([]AdrStatus, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
 	if err != nil {
-		logger.Printf("Error compiling regexes for keyword search: %v\n", err)
+		logger.Debug("Error opening ADR management", "err", err)
+		return nil, err
 	}
-	allAdrFiles, err := am.GetAllAdrFileNames(logger)
+
+	res, err := am.GetStatusFromListOfAdrFiles(ctx, files)
+
+	return res, err
+}
+
+type FileRename struct {
+	From string
+	To   string
+}
+
+// Change of a file's content, the path being the file's new path if it is
+// renamed.
+type FileChange struct {
+	Path   string
+	Before string
+	After  string
+}
+
+// Files touched by an update of the ADR repository, as paths including
+// the ADR folder.
+type UpdateResult struct {
+	Renames []FileRename
+	// All changed files, including the new names of renamed files and the README.
+	Changed []string
+	// Content changes of the changed files (only filled by UpdateAdrRepository).
+	Changes []FileChange
+	// Removed files.
+	Removed []string
+}
+
+// Update all ADRs in a repository. "Update" here means to compare
+// the filename with the configured format and the actual name of
+// the ADR extracted from the file content. ADRs whose filename does
+// not fit are renamed, and all links to renamed ADRs in the ADR folder
+// and the configured doc folders are rewritten. Links referencing ADRs
+// of other ADR logs (like 'billing:0004') are resolved, and the
+// README is updated.
+//
+// The function returns the touched files, or an error if something went
+// wrong.
+func UpdateAdrRepository(ctx context.Context) (UpdateResult, error) {
+	logger := utils.Logger(ctx)
+	res := UpdateResult{Renames: make([]FileRename, 0), Changed: make([]string, 0), Changes: make([]FileChange, 0)}
+
+	am, err := OpenAdrManager(ctx)
 	if err != nil {
-		logger.Printf("Error loading ADR file names: %v\n", err)
+		logger.Debug("Error opening ADR management", "err", err)
+		return res, err
 	}
 
-	res := make([]string, 0)
-FILELOOP:
-	for _, adrFile := range allAdrFiles {
-		rawContent, err := os.ReadFile(filepath.Join(am.Config.Path, adrFile))
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+
+	filenames, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		logger.Debug("Error reading all ADR filenames", "err", err)
+		return res, errors.New(fmt.Sprintf("Error reading all ADR filenames: %v", err))
+	}
+
+	for _, f := range filenames {
+		adrInfos, err := data.LoadAdrInfo(ctx, am.AdrDirectory(), f)
 		if err != nil {
-			logger.Printf("Error reading ADR from '%s': %v\n", adrFile, err)
-			return nil, err
+			logger.Warn("Could not update ADR", "file", f, "err", err)
+			continue
 		}
-		content := string(rawContent)
-		for _, r := range regexes {
-			if !r.MatchString(content) {
-				continue FILELOOP
-			}
+		newFilename := am.expectedFilename(ctx, adrInfos)
+		if newFilename != f {
+			res.Renames = append(res.Renames, FileRename{From: filepath.Join(am.AdrDirectory(), f), To: filepath.Join(am.AdrDirectory(), newFilename)})
 		}
+	}
+
+	// rewrite links to renamed ADRs, and resolve references to ADRs in other ADR logs
+	res.Changes = am.planLinkUpdates(ctx, filenames, res.Renames, map[string]string{}, true)
 
-		res = append(res, adrFile)
+	// toc, generated from the files before the renames
+	toc := am.GenerateToc(ctx)
+	for _, r := range res.Renames {
+		toc = strings.ReplaceAll(toc, "]("+filepath.ToSlash(r.From)+")", "]("+filepath.ToSlash(r.To)+")")
 	}
 
-	return res, nil
+	return am.completeUpdate(ctx, res, toc)
 }
 
-func GetStatusFromListOfAdrFiles(files []string, logger *log.Logger) ([]AdrStatus, error) {
-	am, err := OpenAdrManager(logger)
-	if err != nil {
-		logger.Printf("Error opening ADR management: %v", err)
-		return nil, errors.New(fmt.Sprintf("Error opening ADR management: %v", err))
+// Complete an update with renames and content changes by the change of the
+// TOC to the new toc, and the list of all changed files; then apply it.
+func (am AdrManager) completeUpdate(ctx context.Context, update UpdateResult, toc string) (UpdateResult, error) {
+	readmePath := filepath.Join(am.AdrDirectory(), "README.md")
+	oldToc, _ := utils.ReadFile(readmePath)
+	if toc != string(oldToc) {
+		update.Changes = append(update.Changes, FileChange{Path: readmePath, Before: string(oldToc), After: toc})
 	}
 
-	res, err := am.GetStatusFromListOfAdrFiles(files, logger)
+	for _, r := range update.Renames {
+		update.Changed = append(update.Changed, r.To)
+	}
+	for _, c := range update.Changes {
+		if !slices.Contains(update.Changed, c.Path) {
+			update.Changed = append(update.Changed, c.Path)
+		}
+	}
 
-	return res, err
+	return update, applyUpdate(ctx, update)
 }
 
-// Update all ADRs in a repository. "Update" here means to compare
-// the filename with the configured format and the actual name of
-// the ADR extracted from the file content. After doing this for
-// all ADRs and renaming files if necessary, the README is updated.
-//
-// The function takes a logger as parameter, and returns an error
-// if something went wrong.
-func UpdateAdrRepository(logger *log.Logger) error {
-	am, err := OpenAdrManager(logger)
-	if err != nil {
-		logger.Printf("Error opening ADR management: %v", err)
-		return errors.New(fmt.Sprintf("Error opening ADR management: %v", err))
+// Regenerate the TOC from the current ADR files, and add it to the update
+// if it changed.
+func (am AdrManager) regenerateToc(ctx context.Context, update *UpdateResult) error {
+	logger := utils.Logger(ctx)
+	readmePath := filepath.Join(am.AdrDirectory(), "README.md")
+	oldToc, _ := utils.ReadFile(readmePath)
+	toc := am.GenerateToc(ctx)
+	if toc == string(oldToc) {
+		return nil
 	}
 
-	filenames, err := am.GetAllAdrFileNames(logger)
+	err := utils.WriteFile(readmePath, []byte(toc), 0644)
 	if err != nil {
-		logger.Printf("Error reading all ADR filenames: %v", err)
-		return errors.New(fmt.Sprintf("Error reading all ADR filenames: %v", err))
+		logger.Debug("Could not write TOC", "file", readmePath, "err", err)
+		return err
+	}
+	update.Changes = append(update.Changes, FileChange{Path: readmePath, Before: string(oldToc), After: toc})
+	if !slices.Contains(update.Changed, readmePath) {
+		update.Changed = append(update.Changed, readmePath)
 	}
 
-	for _, f := range filenames {
-		err := am.UpdateFilenameByTitle(f, logger)
+	return nil
+}
+
+// Apply the renames and content changes of an update. If one of them
+// fails, the already applied ones are reverted.
+func applyUpdate(ctx context.Context, update UpdateResult) error {
+	logger := utils.Logger(ctx)
+	var err error
+	renamed := make([]FileRename, 0)
+	written := make([]FileChange, 0)
+	for _, r := range update.Renames {
+		logger.Debug("Renaming ADR file", "from", r.From, "to", r.To)
+		err = utils.RenameFile(r.From, r.To)
+		if err != nil {
+			logger.Warn("Could not rename ADR file", "to", r.To, "err", err)
+			err = errors.New(fmt.Sprintf("Could not rename file to '%s': %v", r.To, err))
+			break
+		}
+		renamed = append(renamed, r)
+	}
+	for _, c := range update.Changes {
+		if err != nil {
+			break
+		}
+		err = utils.WriteFile(c.Path, []byte(c.After), 0644)
 		if err != nil {
-			logger.Printf("Could not update ADR '%s': %v\n", f, err)
+			logger.Warn("Could not write file", "file", c.Path, "err", err)
+			break
 		}
+		written = append(written, c)
+	}
+	if err == nil {
+		return nil
 	}
 
-	// update toc
-	toc := am.GenerateToc(logger)
-	os.WriteFile(filepath.Join(am.Config.Path, "README.md"), []byte(toc), 0644)
+	logger.Debug("Reverting the applied changes")
+	for _, c := range written {
+		utils.WriteFile(c.Path, []byte(c.Before), 0644)
+	}
+	for i := len(renamed) - 1; i >= 0; i-- {
+		utils.RenameFile(renamed[i].To, renamed[i].From)
+	}
 
-	return nil
+	return err
+}
+
+// Determine the changes of the ADRs (filenames relative to the ADR folder)
+// and the documents in the configured doc folders needed to update their
+// links to the renamed files. contents overrides the content of files (by
+// path) which are changed anyway. If resolveCrossLog is set, links
+// referencing ADRs of other ADR logs are resolved as well.
+func (am AdrManager) planLinkUpdates(ctx context.Context, filenames []string, renames []FileRename, contents map[string]string, resolveCrossLog bool) []FileChange {
+	logger := utils.Logger(ctx)
+	res := make([]FileChange, 0)
+
+	// rename map, by absolute paths
+	renameMap := make(map[string]string)
+	for _, r := range renames {
+		from, _ := filepath.Abs(r.From)
+		to, _ := filepath.Abs(r.To)
+		renameMap[from] = to
+	}
+
+	docs := am.getLinkingDocuments(ctx, filenames)
+	for i, doc := range docs {
+		content, err := utils.ReadFile(doc)
+		if err != nil {
+			logger.Warn("Could not read document", "file", doc, "err", err)
+			continue
+		}
+		updated, overridden := contents[doc]
+		if !overridden {
+			updated = string(content)
+		}
+		absDoc, _ := filepath.Abs(doc)
+		newAbsDoc, _ := filepath.Abs(renamedPath(doc, renames))
+		updated = rewriteLinksToRenamedFiles(ctx, updated, absDoc, newAbsDoc, renameMap)
+		if resolveCrossLog && i < len(filenames) {
+			updated = ResolveCrossLogLinks(ctx, updated, am.AdrDirectory())
+		}
+		if updated != string(content) {
+			logger.Debug("Updated links in document", "file", doc)
+			res = append(res, FileChange{Path: renamedPath(doc, renames), Before: string(content), After: updated})
+		}
+	}
+
+	return res
+}
+
+// Get the documents which may link to ADRs: the ADRs (filenames relative
+// to the ADR folder) followed by the markdown files of the doc folders.
+func (am AdrManager) getLinkingDocuments(ctx context.Context, filenames []string) []string {
+	res := make([]string, 0)
+	for _, f := range filenames {
+		res = append(res, filepath.Join(am.AdrDirectory(), f))
+	}
+
+	return append(res, am.getDocFolderFiles(ctx)...)
+}
+
+// Get the path of the file after the renames.
+func renamedPath(file string, renames []FileRename) string {
+	for _, r := range renames {
+		if r.From == file {
+			return r.To
+		}
+	}
+
+	return file
+}
+
+// Get all markdown files in the configured doc folders.
+func (am AdrManager) getDocFolderFiles(ctx context.Context) []string {
+	logger := utils.Logger(ctx)
+	res := make([]string, 0)
+	for _, folder := range am.Config.DocFolders {
+		root := filepath.Join(am.BaseDir, folder)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && filepath.Ext(p) == ".md" {
+				res = append(res, p)
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Warn("Could not read doc folder", "root", root, "err", err)
+		}
+	}
+
+	return res
 }
 
 func compileKeywordsToRegexes(keywords []string, caseSensitive bool) ([]*regexp.Regexp, error) {
@@ -162,10 +376,10 @@ func compileKeywordsToRegexes(keywords []string, caseSensitive bool) ([]*regexp.
 	res := make([]*regexp.Regexp, 0)
 	for _, kw := range keywords {
 		r, err := regexp.Compile(prefix + kw)
-		res = append(res, r)
 		if err != nil {
-			return nil, err
+			return nil, &data.InvalidKeywordError{Keyword: kw, Err: err}
 		}
+		res = append(res, r)
 	}
 
 	return res, nil
@@ -0,0 +1,100 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Git history of a single ADR file.
+type AdrHistory struct {
+	File string
+	// Commit which created the ADR; its author is the ADR's author.
+	Created utils.GitCommit
+	// All commits which changed the ADR, newest first.
+	Commits []utils.GitCommit
+}
+
+// A status change of an ADR together with the git commit which added
+// the respective status line.
+type StatusAttribution struct {
+	data.StatusChange
+	Commit    utils.GitBlameLine
+	Committed bool
+	// True if the date of the status line is the date of its commit.
+	DateMatches bool
+}
+
+// Get the git history of an ADR, selected by its index.
+//
+// Takes the index as string and a flag whether the patches of the commits
+// shall be included. Returns the history or an error, in
+// particular if the ADRs are not managed in a git repository.
+func GetAdrHistory(ctx context.Context, adrIndexStr string, withPatch bool) (AdrHistory, error) {
+	logger := utils.Logger(ctx)
+	if !utils.IsGitRepository() {
+		return AdrHistory{}, errors.New("Not inside a git repository, ADR history is not available.")
+	}
+
+	adrFile, err := GetAdrFilePathByIndexString(ctx, adrIndexStr)
+	if err != nil {
+		return AdrHistory{}, err
+	}
+
+	commits, err := utils.GitLogOfFile(adrFile, withPatch)
+	if err != nil {
+		logger.Debug("Error reading git log", "file", adrFile, "err", err)
+		return AdrHistory{}, err
+	}
+	res := AdrHistory{File: adrFile, Commits: commits}
+	if len(commits) > 0 {
+		res.Created = commits[len(commits)-1]
+	}
+
+	return res, nil
+}
+
+// Get the status entries of an ADR file, each with the commit which
+// added the status line (from git blame).
+func GetStatusAttribution(ctx context.Context, adrFile string) ([]StatusAttribution, error) {
+	logger := utils.Logger(ctx)
+	if !utils.IsGitRepository() {
+		return nil, errors.New("Not inside a git repository, status lines can not be checked against git.")
+	}
+
+	status, err := data.ReadStatusEntries(ctx, adrFile)
+	if err != nil {
+		return nil, err
+	}
+	blame, err := utils.GitBlame(adrFile)
+	if err != nil {
+		logger.Debug("Error running git blame", "file", adrFile, "err", err)
+		return nil, err
+	}
+
+	res := make([]StatusAttribution, 0)
+	used := make(map[int]bool)
+	for _, st := range status {
+		next := StatusAttribution{StatusChange: st}
+		for i, bl := range blame {
+			tokens := strings.Fields(bl.Content)
+			if used[i] || len(tokens) < 2 || tokens[0] != st.Date || tokens[1] != st.Status {
+				continue
+			}
+			used[i] = true
+			next.Commit = bl
+			next.Committed = bl.Hash != utils.UncommittedHash
+			next.DateMatches = next.Committed && bl.Date.Format("2006-01-02") == st.Date
+			break
+		}
+		res = append(res, next)
+	}
+
+	return res, nil
+}
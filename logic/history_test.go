@@ -0,0 +1,52 @@
+package logic
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dukemarty/adr-go/data"
+)
+
+// Commit the files with the given message, author and date.
+func commitAs(t *testing.T, author string, date string, message string, files ...string) {
+	t.Helper()
+	t.Setenv("GIT_AUTHOR_NAME", author)
+	t.Setenv("GIT_AUTHOR_DATE", date)
+	t.Setenv("GIT_COMMITTER_DATE", date)
+	mustRunGit(t, append([]string{"add", "-A", "--"}, files...)...)
+	mustRunGit(t, append([]string{"commit", "-q", "-m", message, "--"}, files...)...)
+}
+
+// An ADR created from the template must not inherit the template's history,
+// which git's copy detection suggests; renames are followed.
+func TestGetAdrHistoryOfAdrCreatedFromTemplate(t *testing.T) {
+	am := setupGitAdrLog(t)
+	ctx := context.Background()
+
+	adrFile, err := am.AddAdr(ctx, "Use Kafka", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitAs(t, "Alice", "2023-07-01T12:00:00Z", "propose kafka", adrFile)
+
+	if err := data.SetAdrHeading(ctx, adrFile, "0001", "Use Pulsar"); err != nil {
+		t.Fatal(err)
+	}
+	renamed, err := am.UpdateFilenameByTitle(ctx, filepath.Base(adrFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitAs(t, "Bob", "2023-07-02T12:00:00Z", "rename to pulsar", adrFile, filepath.Join(am.AdrDirectory(), renamed))
+
+	history, err := GetAdrHistory(ctx, "1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Commits) != 2 {
+		t.Errorf("expected the 2 commits of the ADR, got %d: %v", len(history.Commits), history.Commits)
+	}
+	if history.Created.Author != "Alice" || history.Created.Subject != "propose kafka" {
+		t.Errorf("expected Alice's commit as creation, got %s: %s", history.Created.Author, history.Created.Subject)
+	}
+}
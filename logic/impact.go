@@ -0,0 +1,202 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// An ADR in the result of an impact analysis, with the ADRs depending on it.
+type ImpactNode struct {
+	Id     string
+	Title  string
+	Status string
+	File   string
+	// Relation of the ADR to its parent in the tree, e.g. "amends"; empty
+	// for the analyzed ADR itself.
+	Relation   string
+	Dependents []*ImpactNode
+}
+
+// Result of the impact analysis of an ADR.
+type AdrImpact struct {
+	Adr *ImpactNode
+	// Markdown files (other than ADRs) linking to the ADR, as 'file:line'.
+	Documents []string
+	// Source locations referencing the ADR (see ScanReferences), as 'file:line'.
+	CodeReferences []string
+}
+
+// Phrases in front of a link to an ADR, and the relation they express,
+// checked in this order; other links are plain "links to".
+var linkRelations = []struct{ Phrase, Relation string }{
+	{"superseded by", "superseded by"},
+	{"supersedes", "supersedes"},
+	{"amended by", "amended by"},
+	{"amends", "amends"},
+}
+
+// An ADR linking to another one.
+type adrLink struct {
+	From     string
+	Relation string
+}
+
+// Analyze which ADRs and documents depend on the ADR with the given id: all
+// ADRs linking to it (e.g. amending it, or being superseded by it), and
+// transitively the ADRs linking to those; all other markdown files of the
+// repository linking to the ADR; and, if withCode is set, the source code
+// references to it.
+func AnalyzeImpact(ctx context.Context, adrId string, withCode bool) (AdrImpact, error) {
+	res := AdrImpact{Documents: make([]string, 0), CodeReferences: make([]string, 0)}
+
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return res, err
+	}
+	target, err := am.GetAdrFilenameById(ctx, adrId)
+	if err != nil {
+		return res, err
+	}
+	allAdrs, err := am.GetListOfAllAdrsStatus(ctx)
+	if err != nil {
+		return res, err
+	}
+	statusByFilename := make(map[string]AdrStatus)
+	for _, adrst := range allAdrs {
+		statusByFilename[adrst.Filename] = adrst
+	}
+
+	// links between ADRs, by linked ADR
+	adrDir, _ := filepath.Abs(am.AdrDirectory())
+	linkedBy := make(map[string][]adrLink)
+	for _, adrst := range allAdrs {
+		for _, l := range findLinksToFiles(ctx, adrst.Path) {
+			linked, err := filepath.Rel(adrDir, l.Target)
+			if err != nil || linked == adrst.Filename {
+				continue
+			}
+			if _, isAdr := statusByFilename[linked]; isAdr {
+				linkedBy[linked] = append(linkedBy[linked], adrLink{From: adrst.Filename, Relation: l.Relation})
+			}
+		}
+	}
+
+	// transitive closure as tree, each ADR appearing once
+	newNode := func(filename string, relation string) *ImpactNode {
+		adrst := statusByFilename[filename]
+		return &ImpactNode{Id: adrst.Id, Title: adrst.Title, Status: adrst.LastStatus, File: adrst.Path, Relation: relation}
+	}
+	res.Adr = newNode(target, "")
+	visited := map[string]bool{target: true}
+	queue := []struct {
+		filename string
+		node     *ImpactNode
+	}{{target, res.Adr}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, l := range linkedBy[current.filename] {
+			if visited[l.From] {
+				continue
+			}
+			visited[l.From] = true
+			child := newNode(l.From, l.Relation)
+			current.node.Dependents = append(current.node.Dependents, child)
+			queue = append(queue, struct {
+				filename string
+				node     *ImpactNode
+			}{l.From, child})
+		}
+	}
+
+	// other markdown files of the repository
+	targetPath, _ := filepath.Abs(filepath.Join(adrDir, target))
+	tocPath := filepath.Join(adrDir, "README.md")
+	root, err := FindRepositoryRoot()
+	if err != nil {
+		return res, err
+	}
+	files, err := listSourceFiles(ctx, root)
+	if err != nil {
+		return res, err
+	}
+	for _, f := range files {
+		abs, _ := filepath.Abs(f)
+		if filepath.Ext(f) != ".md" || abs == tocPath || strings.HasPrefix(abs, adrDir+string(filepath.Separator)) {
+			continue
+		}
+		for _, l := range findLinksToFiles(ctx, f) {
+			if l.Target == targetPath {
+				res.Documents = append(res.Documents, fmt.Sprintf("%s:%d", filepath.ToSlash(f), l.Line))
+			}
+		}
+	}
+
+	// source code
+	if withCode {
+		refs, err := am.ScanReferences(ctx, ReferenceScanOptions{})
+		if err != nil {
+			return res, err
+		}
+		for _, ref := range refs {
+			if ref.AdrFilename == target {
+				res.CodeReferences = append(res.CodeReferences, ref.Location())
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// A link from a markdown file to a local file.
+type fileLink struct {
+	// Absolute path of the linked file.
+	Target   string
+	Line     int
+	Relation string
+}
+
+// Find the links to local files in the markdown file.
+func findLinksToFiles(ctx context.Context, file string) []fileLink {
+	logger := utils.Logger(ctx)
+	res := make([]fileLink, 0)
+	doc, err := utils.OpenMarkdownFile(file)
+	if err != nil {
+		logger.Warn("Could not read file for links", "file", file, "err", err)
+		return res
+	}
+
+	for _, l := range doc.FindLinks() {
+		u, err := url.Parse(l.Destination)
+		if err != nil || len(u.Scheme) > 0 || len(u.Path) == 0 {
+			continue
+		}
+		target, err := filepath.Abs(filepath.Join(filepath.Dir(file), filepath.FromSlash(u.Path)))
+		if err != nil {
+			continue
+		}
+		res = append(res, fileLink{Target: target, Line: l.Line, Relation: linkRelation(l.Prefix)})
+	}
+
+	return res
+}
+
+// Get the relation of a link to an ADR from the text in front of it.
+func linkRelation(prefix string) string {
+	prefix = strings.ToLower(prefix)
+	for _, r := range linkRelations {
+		if strings.Contains(prefix, r.Phrase) {
+			return r.Relation
+		}
+	}
+
+	return "links to"
+}
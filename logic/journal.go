@@ -0,0 +1,73 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Get the operations recorded in the journal of the ADR log, the most
+// recent one first.
+func GetJournalOperations(ctx context.Context) ([]utils.JournalEntry, error) {
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := utils.ReadJournal(am.DataDirectory())
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+
+	return entries, nil
+}
+
+// Undo the last count operations recorded in the journal of the ADR log,
+// most recent first, restoring the files changed by them. The restoration
+// is not recorded in the journal.
+//
+// Returns the undone operations, or an error (with the operations undone
+// before it occurred).
+func UndoOperations(ctx context.Context, count int) ([]utils.JournalEntry, error) {
+	logger := utils.Logger(ctx)
+	res := make([]utils.JournalEntry, 0)
+
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return res, err
+	}
+	utils.StopJournal()
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+
+	entries, err := utils.ReadJournal(am.DataDirectory())
+	if err != nil {
+		return res, err
+	}
+	if count > len(entries) {
+		logger.Debug("Fewer operations recorded than requested, undoing all of them", "count", len(entries))
+		count = len(entries)
+	}
+
+	for i := len(entries) - 1; i >= len(entries)-count; i-- {
+		err = utils.UndoJournalEntry(am.DataDirectory(), entries[i])
+		if err != nil {
+			return res, errors.New(fmt.Sprintf("Error undoing '%s': %v", entries[i].Operation, err))
+		}
+		res = append(res, entries[i])
+	}
+
+	return res, nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Move the ADR with the given id to the archive folder, keeping its
+// category. Links to it in other ADRs and the configured doc folders are
+// updated, and the TOC is regenerated (listing the ADR as archived).
+//
+// Returns the changes, or an error.
+func ArchiveAdr(ctx context.Context, adrId string) (UpdateResult, error) {
+	logger := utils.Logger(ctx)
+	res := UpdateResult{Renames: make([]FileRename, 0), Changed: make([]string, 0), Changes: make([]FileChange, 0)}
+
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+	filename, err := am.GetAdrFilenameById(ctx, adrId)
+	if err != nil {
+		return res, err
+	}
+	if data.IsArchivedAdrFile(filename) {
+		return res, errors.New(fmt.Sprintf("ADR %s is already archived", adrId))
+	}
+
+	rename := FileRename{From: filepath.Join(am.AdrDirectory(), filename), To: filepath.Join(am.AdrDirectory(), data.ArchiveFolder, filename)}
+	err = utils.MkdirAll(filepath.Dir(rename.To))
+	if err != nil {
+		logger.Debug("Could not create archive folder", "err", err)
+		return res, err
+	}
+	res.Renames = append(res.Renames, rename)
+
+	filenames, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		return res, err
+	}
+	res.Changes = am.planLinkUpdates(ctx, filenames, res.Renames, map[string]string{}, false)
+
+	return am.applyAndRegenerateToc(ctx, res)
+}
+
+// Withdraw the ADR with the given id: a status entry 'Withdrawn' (with the
+// optional note) is added, and the TOC is regenerated, leaving the ADR out.
+//
+// Returns the changes, or an error.
+func WithdrawAdr(ctx context.Context, adrId string, note string) (UpdateResult, error) {
+	res := UpdateResult{Renames: make([]FileRename, 0), Changed: make([]string, 0), Changes: make([]FileChange, 0)}
+
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+	filename, err := am.GetAdrFilenameById(ctx, adrId)
+	if err != nil {
+		return res, err
+	}
+	adrPath := filepath.Join(am.AdrDirectory(), filename)
+	status, err := data.ReadStatusEntries(ctx, adrPath)
+	if err == nil && len(status) > 0 && strings.EqualFold(status[len(status)-1].Status, data.WithdrawnStatus) {
+		return res, errors.New(fmt.Sprintf("ADR %s is already withdrawn", adrId))
+	}
+
+	content, err := utils.ReadFile(adrPath)
+	if err != nil {
+		return res, err
+	}
+	updated, err := data.WithStatusEntry(string(content), data.WithdrawnStatus, note)
+	if err != nil {
+		return res, errors.New(fmt.Sprintf("Could not add status entry to '%s': %v", adrPath, err))
+	}
+	res.Changes = append(res.Changes, FileChange{Path: adrPath, Before: string(content), After: updated})
+
+	return am.applyAndRegenerateToc(ctx, res)
+}
+
+// Delete the ADR with the given id. Links to it in other ADRs and the
+// configured doc folders are removed (keeping the link texts), and the TOC
+// is regenerated.
+//
+// Returns the changes, or an error.
+func DeleteAdr(ctx context.Context, adrId string) (UpdateResult, error) {
+	logger := utils.Logger(ctx)
+	res := UpdateResult{Renames: make([]FileRename, 0), Changed: make([]string, 0), Changes: make([]FileChange, 0), Removed: make([]string, 0)}
+
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+	filename, err := am.GetAdrFilenameById(ctx, adrId)
+	if err != nil {
+		return res, err
+	}
+	adrPath := filepath.Join(am.AdrDirectory(), filename)
+	absAdrPath, _ := filepath.Abs(adrPath)
+	res.Removed = append(res.Removed, adrPath)
+
+	filenames, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		return res, err
+	}
+	for _, doc := range am.getLinkingDocuments(ctx, filenames) {
+		if doc == adrPath {
+			continue
+		}
+		content, err := utils.ReadFile(doc)
+		if err != nil {
+			logger.Warn("Could not read document", "file", doc, "err", err)
+			continue
+		}
+		absDoc, _ := filepath.Abs(doc)
+		updated := removeLinksToFile(ctx, string(content), absDoc, absAdrPath)
+		if updated != string(content) {
+			res.Changes = append(res.Changes, FileChange{Path: doc, Before: string(content), After: updated})
+		}
+	}
+
+	err = utils.RemoveFile(adrPath)
+	if err != nil {
+		logger.Debug("Could not delete ADR", "file", adrPath, "err", err)
+		return res, err
+	}
+	res.Changed = append(res.Changed, adrPath)
+
+	return am.applyAndRegenerateToc(ctx, res)
+}
+
+// Apply the renames and content changes of an update, then regenerate the
+// TOC from the changed ADR files.
+func (am AdrManager) applyAndRegenerateToc(ctx context.Context, update UpdateResult) (UpdateResult, error) {
+	readmePath := filepath.Join(am.AdrDirectory(), "README.md")
+	oldToc, _ := utils.ReadFile(readmePath)
+
+	update, err := am.completeUpdate(ctx, update, string(oldToc))
+	if err != nil {
+		return update, err
+	}
+
+	return update, am.regenerateToc(ctx, &update)
+}
@@ -0,0 +1,261 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// A dangling link in an ADR.
+type BrokenLink struct {
+	// ADR file containing the link, including the ADR folder.
+	File        string
+	Line        int
+	Destination string
+	Problem     string
+	// Replacement for the destination, if the link points to an ADR which
+	// was renamed; empty otherwise.
+	Fix string
+}
+
+func (link BrokenLink) String() string {
+	res := fmt.Sprintf("%s:%d: %s: %s", link.File, link.Line, link.Destination, link.Problem)
+	if len(link.Fix) > 0 {
+		res += fmt.Sprintf(" (fix: %s)", link.Fix)
+	}
+
+	return res
+}
+
+// Check the links of all ADRs: links to local files must point to existing
+// files, and anchors to existing headings. If external is set, URLs are
+// checked with a HEAD request, waiting at most timeout.
+//
+// Returns the broken links, or an error.
+func (am AdrManager) CheckLinks(ctx context.Context, external bool, timeout time.Duration) ([]BrokenLink, error) {
+	logger := utils.Logger(ctx)
+	filenames, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	headingIds := make(map[string]map[string]bool)
+	res := make([]BrokenLink, 0)
+	for _, f := range filenames {
+		adrPath := filepath.Join(am.AdrDirectory(), f)
+		doc, err := utils.OpenMarkdownFile(adrPath)
+		if err != nil {
+			logger.Warn("Could not read ADR for links", "file", f, "err", err)
+			continue
+		}
+
+		for _, l := range doc.FindLinks() {
+			problem := ""
+			fix := ""
+			u, err := url.Parse(l.Destination)
+			switch {
+			case err != nil:
+				problem = fmt.Sprintf("invalid link: %v", err)
+			case u.Scheme == "http" || u.Scheme == "https":
+				if external {
+					problem = checkExternalLink(client, l.Destination)
+				}
+			case len(u.Scheme) > 0:
+				// e.g. mailto, not checked
+			default:
+				target := adrPath
+				if len(u.Path) > 0 {
+					target = filepath.Join(filepath.Dir(adrPath), filepath.FromSlash(u.Path))
+				}
+				if !utils.FileExists(target) {
+					problem = "file does not exist"
+					if renamed, err := am.findRenamedAdr(target, filenames); err == nil {
+						fix = relativeLink(filepath.Dir(adrPath), filepath.Join(am.AdrDirectory(), renamed))
+						if len(u.Fragment) > 0 {
+							fix += "#" + u.Fragment
+						}
+					}
+				} else if len(u.Fragment) > 0 && filepath.Ext(target) == ".md" {
+					if _, known := headingIds[target]; !known {
+						headingIds[target], _ = utils.FindMarkdownHeadingIds(target)
+					}
+					if !headingIds[target][u.Fragment] {
+						problem = fmt.Sprintf("no heading for anchor '#%s'", u.Fragment)
+					}
+				}
+			}
+
+			if len(problem) > 0 {
+				res = append(res, BrokenLink{File: adrPath, Line: l.Line, Destination: l.Destination, Problem: problem, Fix: fix})
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// Repair the links of all ADRs pointing to ADRs which were renamed (e.g.
+// by update after a change of the title).
+//
+// Returns the repaired links, or an error.
+func (am AdrManager) FixLinks(ctx context.Context) ([]BrokenLink, error) {
+	logger := utils.Logger(ctx)
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	broken, err := am.CheckLinks(ctx, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fixesByFile := make(map[string][]BrokenLink)
+	for _, b := range broken {
+		if len(b.Fix) > 0 {
+			fixesByFile[b.File] = append(fixesByFile[b.File], b)
+		}
+	}
+
+	res := make([]BrokenLink, 0)
+	for file, fixes := range fixesByFile {
+		content, err := utils.ReadFile(file)
+		if err != nil {
+			return res, err
+		}
+		updated := string(content)
+		for _, b := range fixes {
+			updated = strings.ReplaceAll(updated, "]("+b.Destination, "]("+b.Fix)
+		}
+		err = utils.WriteFile(file, []byte(updated), 0644)
+		if err != nil {
+			logger.Debug("Could not write repaired links", "file", file, "err", err)
+			return res, err
+		}
+		logger.Debug("Repaired links", "count", len(fixes), "file", file)
+		res = append(res, fixes...)
+	}
+
+	return res, nil
+}
+
+// Find the current filename (relative to the ADR folder) of the ADR which
+// was linked as the missing file target, by its category and index among
+// the ADR filenames.
+func (am AdrManager) findRenamedAdr(target string, filenames []string) (string, error) {
+	adrDir, _ := filepath.Abs(am.AdrDirectory())
+	absTarget, _ := filepath.Abs(target)
+	linked, err := filepath.Rel(adrDir, absTarget)
+	if err != nil || strings.HasPrefix(linked, "..") {
+		return "", errors.New(fmt.Sprintf("'%s' is not in the ADR folder", target))
+	}
+	index, err := am.ExtractAdrIndexFromFile(linked)
+	if err != nil {
+		return "", err
+	}
+
+	category := data.CategoryOfAdrFile(linked)
+	for _, f := range filenames {
+		if data.CategoryOfAdrFile(f) != category {
+			continue
+		}
+		if i, err := am.ExtractAdrIndexFromFile(f); err == nil && i == index {
+			return f, nil
+		}
+	}
+
+	return "", errors.New(fmt.Sprintf("No ADR with index %d", index))
+}
+
+// Check an URL with a HEAD request; returns a problem description, or an
+// empty string if the URL is reachable.
+func checkExternalLink(client *http.Client, link string) string {
+	resp, err := client.Head(link)
+	if err != nil {
+		return fmt.Sprintf("not reachable: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Sprintf("HTTP status %d", resp.StatusCode)
+	}
+
+	return ""
+}
+
+// Rewrite the links in the markdown content of file (an absolute path) which
+// point to renamed files; renames maps the old to the new absolute paths.
+// If the file itself is moved to newFile, its relative links are adapted
+// to the new location. Anchors and titles of the links are kept.
+func rewriteLinksToRenamedFiles(ctx context.Context, content string, file string, newFile string, renames map[string]string) string {
+	logger := utils.Logger(ctx)
+	moved := filepath.Dir(file) != filepath.Dir(newFile)
+	if len(renames) == 0 && !moved {
+		return content
+	}
+	doc, err := utils.ParseMarkdown([]byte(content))
+	if err != nil {
+		logger.Warn("Could not parse file for links", "file", file, "err", err)
+		return content
+	}
+
+	for _, l := range doc.FindLinks() {
+		u, err := url.Parse(l.Destination)
+		if err != nil || len(u.Scheme) > 0 || len(u.Path) == 0 {
+			continue
+		}
+		target := filepath.Join(filepath.Dir(file), filepath.FromSlash(u.Path))
+		newTarget, renamed := renames[target]
+		if !renamed && !moved {
+			continue
+		}
+		if !renamed {
+			newTarget = target
+		}
+		newLink := relativeLink(filepath.Dir(newFile), newTarget)
+		if len(u.Fragment) > 0 {
+			newLink += "#" + u.Fragment
+		}
+		content = strings.ReplaceAll(content, "]("+l.Destination, "]("+newLink)
+	}
+
+	return content
+}
+
+// Remove the links in the markdown content of file (an absolute path) which
+// point to the removed file (an absolute path), keeping the link texts.
+func removeLinksToFile(ctx context.Context, content string, file string, removed string) string {
+	logger := utils.Logger(ctx)
+	doc, err := utils.ParseMarkdown([]byte(content))
+	if err != nil {
+		logger.Warn("Could not parse file for links", "file", file, "err", err)
+		return content
+	}
+
+	for _, l := range doc.FindLinks() {
+		u, err := url.Parse(l.Destination)
+		if err != nil || len(u.Scheme) > 0 || len(u.Path) == 0 {
+			continue
+		}
+		if filepath.Join(filepath.Dir(file), filepath.FromSlash(u.Path)) != removed {
+			continue
+		}
+		linkRegex := regexp.MustCompile(`!?\[([^\]]*)\]\(` + regexp.QuoteMeta(l.Destination) + `(?:\s+"[^"]*")?\)`)
+		content = linkRegex.ReplaceAllString(content, "$1")
+	}
+
+	return content
+}
@@ -0,0 +1,215 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Write the ADR files (name relative to the ADR folder, content) for a test.
+func writeTestAdrs(t *testing.T, am *AdrManager, adrs map[string]string) {
+	t.Helper()
+	for name, content := range adrs {
+		path := filepath.Join(am.AdrDirectory(), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Get the broken links as '<file>:<line>: <destination>' for comparison,
+// with the file relative to the ADR folder.
+func describeBrokenLinks(am *AdrManager, broken []BrokenLink) []string {
+	res := make([]string, 0)
+	for _, b := range broken {
+		file, _ := filepath.Rel(am.AdrDirectory(), b.File)
+		res = append(res, fmt.Sprintf("%s:%d: %s", filepath.ToSlash(file), b.Line, b.Destination))
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+const pulsarAdr = `# 2. Use Pulsar
+
+## Status
+
+2023-07-01 PROPOSED
+
+## Context
+
+See [the decision](0001-use-kafka.md#decision).
+`
+
+func TestCheckLinksFindsDanglingFilesAndAnchors(t *testing.T) {
+	am := newTestAdrManager(t)
+	writeTestAdrs(t, am, map[string]string{
+		"0001-use-kafka.md": `# 1. Use Kafka
+
+## Status
+
+2023-07-01 PROPOSED
+
+## Decision
+
+* [existing](0002-use-pulsar.md)
+* [existing anchor](0002-use-pulsar.md#context)
+* [missing anchor](0002-use-pulsar.md#consequences)
+* [missing file](0003-missing.md)
+* [own heading](#decision)
+* [missing own heading](#nothing)
+* [mail](mailto:adr@example.com)
+* [not checked](https://example.invalid/)
+`,
+		"0002-use-pulsar.md": pulsarAdr,
+	})
+
+	broken, err := am.CheckLinks(context.Background(), false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"0001-use-kafka.md:11: 0002-use-pulsar.md#consequences",
+		"0001-use-kafka.md:12: 0003-missing.md",
+		"0001-use-kafka.md:14: #nothing",
+	}
+	if got := describeBrokenLinks(am, broken); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected broken links:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+	for _, b := range broken {
+		if len(b.Fix) > 0 {
+			t.Errorf("unexpected fix for %s: %s", b.Destination, b.Fix)
+		}
+	}
+}
+
+func TestCheckLinksExternal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	am := newTestAdrManager(t)
+	writeTestAdrs(t, am, map[string]string{
+		"0001-use-kafka.md": fmt.Sprintf(`# 1. Use Kafka
+
+* [ok](%[1]s/ok)
+* [gone](%[1]s/gone)
+* [slow](%[1]s/slow)
+`, server.URL),
+	})
+
+	broken, err := am.CheckLinks(context.Background(), false, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) > 0 {
+		t.Errorf("external links checked without --external: %v", broken)
+	}
+
+	start := time.Now()
+	broken, err = am.CheckLinks(context.Background(), true, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("timeout not applied, check took %v", elapsed)
+	}
+	if len(broken) != 2 {
+		t.Fatalf("expected 2 broken external links, got %v", broken)
+	}
+	problems := make(map[string]string)
+	for _, b := range broken {
+		problems[strings.TrimPrefix(b.Destination, server.URL)] = b.Problem
+	}
+	if problems["/gone"] != "HTTP status 404" {
+		t.Errorf("unexpected problem for missing page: '%s'", problems["/gone"])
+	}
+	if !strings.HasPrefix(problems["/slow"], "not reachable") {
+		t.Errorf("unexpected problem for slow page: '%s'", problems["/slow"])
+	}
+}
+
+func TestFixLinksToRenamedAdrs(t *testing.T) {
+	am := newTestAdrManager(t)
+	writeTestAdrs(t, am, map[string]string{
+		"0001-use-kafka.md": `# 1. Use Kafka
+
+Superseded by [Pulsar](0002-use-rabbitmq.md#context) and [Pulsar again](0002-use-rabbitmq.md).
+See also [missing](0003-missing.md).
+
+## Decision
+`,
+		"0002-use-pulsar.md":  pulsarAdr,
+		"sec/0001-use-tls.md": "# 1. Use TLS\n\nLike [Kafka](../0001-use-apache-kafka.md).\n",
+	})
+
+	broken, err := am.CheckLinks(context.Background(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixes := make(map[string]string)
+	for _, b := range broken {
+		fixes[b.Destination] = b.Fix
+	}
+	if fixes["0002-use-rabbitmq.md#context"] != "0002-use-pulsar.md#context" || fixes["../0001-use-apache-kafka.md"] != "../0001-use-kafka.md" {
+		t.Errorf("unexpected fixes: %v", fixes)
+	}
+
+	fixed, err := am.FixLinks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed) != 3 {
+		t.Errorf("expected 3 repaired links, got %v", fixed)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(am.AdrDirectory(), "0001-use-kafka.md"))
+	expected := `# 1. Use Kafka
+
+Superseded by [Pulsar](0002-use-pulsar.md#context) and [Pulsar again](0002-use-pulsar.md).
+See also [missing](0003-missing.md).
+
+## Decision
+`
+	if string(content) != expected {
+		t.Errorf("unexpected repaired ADR:\n%s", content)
+	}
+	content, _ = os.ReadFile(filepath.Join(am.AdrDirectory(), "sec", "0001-use-tls.md"))
+	if !strings.Contains(string(content), "[Kafka](../0001-use-kafka.md)") {
+		t.Errorf("link in category not repaired:\n%s", content)
+	}
+
+	broken, err = am.CheckLinks(context.Background(), false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describeBrokenLinks(am, broken); len(got) != 1 || got[0] != "0001-use-kafka.md:4: 0003-missing.md" {
+		t.Errorf("unexpected broken links after fix: %v", got)
+	}
+}
@@ -0,0 +1,44 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Name of the lock file in the data folder of the project.
+const lockFileName = "lock"
+
+// Maximum time to wait for another process changing the ADR log.
+var lockTimeout = 30 * time.Second
+
+// Lock the ADR log against concurrent changes by other adr-go processes (or
+// goroutines), waiting for a lock held by them to be released. All
+// operations changing the ADR log hold the lock. In a dry run, nothing is
+// locked.
+//
+// Returns the function to release the lock, or an error if the lock could
+// not be acquired.
+func (am AdrManager) Lock(ctx context.Context) (func(), error) {
+	logger := utils.Logger(ctx)
+	if utils.IsDryRun() {
+		return func() {}, nil
+	}
+	utils.IgnoreDataFolder(am.DataDirectory())
+	lockFile := filepath.Join(am.DataDirectory(), lockFileName)
+	logger.Debug("Acquiring lock", "file", lockFile)
+
+	unlock, err := utils.AcquireLock(lockFile, lockTimeout)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not lock ADR log: %v", err))
+	}
+
+	return unlock, nil
+}
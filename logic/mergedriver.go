@@ -0,0 +1,177 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Name of the merge driver in the git configuration and .gitattributes.
+const MergeDriverName = "adr-go-toc"
+
+// Merge the TOC files of a three-way merge, as done by git for a merge
+// driver: base is the common ancestor's version, current ours, and other
+// theirs. The result is written to current.
+//
+// Returns the duplicate ADR numbers of the merged TOC, or an error.
+func MergeTocFiles(ctx context.Context, base string, current string, other string) ([]string, error) {
+	logger := utils.Logger(ctx)
+	contents := make([]string, 0)
+	for _, f := range []string{base, current, other} {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			logger.Debug("Could not read file for merging TOC", "file", f, "err", err)
+			return nil, err
+		}
+		contents = append(contents, string(content))
+	}
+
+	merged, duplicates := MergeToc(contents[0], contents[1], contents[2])
+
+	return duplicates, os.WriteFile(current, []byte(merged), 0644)
+}
+
+// Install the TOC merge driver for the ADR repository: the driver is
+// configured in the repository's git config, assigned to the README (TOC)
+// file in .gitattributes, and a post-merge hook checking for duplicate ADR
+// numbers is added.
+//
+// Takes the command line of the adr-go executable to use. Returns the
+// changed files, or an error.
+func InstallMergeDriver(ctx context.Context, executable string) ([]string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsGitRepository() {
+		return nil, errors.New("Not inside a git repository, merge driver can not be installed.")
+	}
+
+	out, err := utils.RunGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSpace(out)
+	changed := make([]string, 0)
+
+	// 1) git config
+	if !utils.IsDryRun() {
+		_, err = utils.RunGit("config", "merge."+MergeDriverName+".name", "adr-go merge driver for the ADR TOC")
+		if err == nil {
+			_, err = utils.RunGit("config", "merge."+MergeDriverName+".driver", shellQuote(executable)+" git-merge-driver %O %A %B %P")
+		}
+		if err != nil {
+			logger.Debug("Could not configure merge driver", "err", err)
+			return nil, err
+		}
+	}
+	changed = append(changed, ".git/config")
+
+	// 2) .gitattributes
+	tocPath, _ := filepath.Abs(filepath.Join(am.AdrDirectory(), "README.md"))
+	relTocPath, err := filepath.Rel(root, tocPath)
+	if err != nil {
+		return changed, err
+	}
+	attributesFile := filepath.Join(root, ".gitattributes")
+	attributesLine := filepath.ToSlash(relTocPath) + " merge=" + MergeDriverName
+	if appended, err := appendLineIfMissing(attributesFile, attributesLine, ""); err != nil {
+		logger.Debug("Could not update file", "file", attributesFile, "err", err)
+		return changed, err
+	} else if appended {
+		changed = append(changed, attributesFile)
+	}
+
+	// 3) post-merge hook
+	out, err = utils.RunGit("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return changed, err
+	}
+	hooksDir := strings.TrimSpace(out)
+	if err := utils.MkdirAll(hooksDir); err != nil {
+		return changed, err
+	}
+	baseDir, _ := filepath.Abs(am.BaseDir)
+	relBaseDir, _ := filepath.Rel(root, baseDir)
+	hookFile := filepath.Join(hooksDir, "post-merge")
+	hookLine := fmt.Sprintf("(cd %s && %s renumber --check)", shellQuote(filepath.ToSlash(relBaseDir)), shellQuote(executable))
+	if appended, err := insertHookLineIfMissing(hookFile, hookLine); err != nil {
+		logger.Debug("Could not update file", "file", hookFile, "err", err)
+		return changed, err
+	} else if appended {
+		os.Chmod(hookFile, 0755)
+		changed = append(changed, hookFile)
+	}
+
+	return changed, nil
+}
+
+// Append line to the file, unless the file already contains it. A missing
+// file is created starting with header.
+// Returns true if the line was appended.
+func appendLineIfMissing(file string, line string, header string) (bool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if err != nil {
+		content = []byte(header)
+	}
+
+	for _, l := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(l) == line {
+			return false, nil
+		}
+	}
+
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		content = append(content, '\n')
+	}
+	content = append(content, []byte(line+"\n")...)
+
+	return true, utils.WriteFile(file, content, 0644)
+}
+
+// Insert line into the hook script file right after its shebang, unless the
+// file already contains it: appended to the end, the line would not run if
+// the existing script ends with e.g. 'exit 0'. A missing file is created as
+// shell script.
+// Returns true if the line was inserted.
+func insertHookLineIfMissing(file string, line string) (bool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if err != nil {
+		content = []byte("#!/bin/sh\n")
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for _, l := range lines {
+		if strings.TrimSpace(l) == line {
+			return false, nil
+		}
+	}
+
+	at := 0
+	if strings.HasPrefix(lines[0], "#!") {
+		at = 1
+	}
+	lines = append(lines[:at], append([]string{line}, lines[at:]...)...)
+
+	return true, utils.WriteFile(file, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// Quote s as a single word for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,257 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Default regex for references to ADRs in source code, e.g. 'ADR-0007'.
+const DefaultReferencePattern = `\bADR[- ]?(\d+)\b`
+
+// A reference to an ADR found in a source file.
+type AdrReference struct {
+	// Source file, relative to the working directory.
+	File string
+	Line int
+	// Referenced ADR id, as written in the source.
+	Id string
+	// Referenced ADR (its filename relative to the ADR folder), empty if
+	// the ADR does not exist.
+	AdrFilename string
+	Title       string
+	Status      string
+	// Problem with the reference, empty if there is none.
+	Problem string
+}
+
+// Location of the reference as 'file:line'.
+func (ref AdrReference) Location() string {
+	return fmt.Sprintf("%s:%d", filepath.ToSlash(ref.File), ref.Line)
+}
+
+// Options of a scan for references to ADRs; empty options are taken from
+// the project configuration.
+type ReferenceScanOptions struct {
+	// Directory to scan, the project directory if empty.
+	Root    string
+	Pattern string
+	Include []string
+	Exclude []string
+}
+
+// Scan the source files below the root directory of the options for
+// references to the ADRs of this log. Files ignored by git (if the root is
+// inside a git repository), hidden directories and the ADR folder itself are
+// skipped.
+//
+// References to unknown, deprecated, superseded, withdrawn or archived ADRs
+// get a problem description. Returns the found references, or an error.
+func (am AdrManager) ScanReferences(ctx context.Context, options ReferenceScanOptions) ([]AdrReference, error) {
+	logger := utils.Logger(ctx)
+	options = am.completeScanOptions(options)
+	r, err := regexp.Compile(options.Pattern)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid reference pattern '%s': %v", options.Pattern, err))
+	}
+	if r.NumSubexp() < 1 {
+		return nil, errors.New(fmt.Sprintf("Reference pattern '%s' has no group for the ADR id", options.Pattern))
+	}
+
+	files, err := listSourceFiles(ctx, options.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	allAdrs, err := am.GetListOfAllAdrsStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statusByFilename := make(map[string]AdrStatus)
+	for _, adrst := range allAdrs {
+		statusByFilename[adrst.Filename] = adrst
+	}
+	adrDir, _ := filepath.Abs(am.AdrDirectory())
+	filenameById := make(map[string]string)
+
+	res := make([]AdrReference, 0)
+	for _, f := range files {
+		rel, _ := filepath.Rel(options.Root, f)
+		if !matchesScanGlobs(filepath.ToSlash(rel), options.Include, options.Exclude) {
+			continue
+		}
+		if abs, _ := filepath.Abs(f); strings.HasPrefix(abs, adrDir+string(filepath.Separator)) {
+			continue
+		}
+
+		refs, err := scanFileForReferences(f, r)
+		if err != nil {
+			logger.Warn("Could not scan file for ADR references", "file", f, "err", err)
+			continue
+		}
+		for _, ref := range refs {
+			filename, known := filenameById[ref.Id]
+			if !known {
+				filename, _ = am.GetAdrFilenameById(ctx, ref.Id)
+				filenameById[ref.Id] = filename
+			}
+			adrst, found := statusByFilename[filename]
+			if !found {
+				ref.Problem = fmt.Sprintf("ADR %s does not exist", ref.Id)
+			} else {
+				ref.AdrFilename, ref.Title, ref.Status = filename, adrst.Title, adrst.LastStatus
+				switch strings.ToUpper(adrst.LastStatus) {
+				case "DEPRECATED", "SUPERSEDED", "WITHDRAWN":
+					ref.Problem = fmt.Sprintf("ADR %s is %s", ref.Id, strings.ToLower(adrst.LastStatus))
+				default:
+					if adrst.Archived {
+						ref.Problem = fmt.Sprintf("ADR %s is archived", ref.Id)
+					}
+				}
+			}
+			res = append(res, ref)
+		}
+	}
+
+	return res, nil
+}
+
+// Fill empty scan options from the project configuration or defaults.
+func (am AdrManager) completeScanOptions(options ReferenceScanOptions) ReferenceScanOptions {
+	if len(options.Root) == 0 {
+		options.Root = am.BaseDir
+	}
+	if len(options.Root) == 0 {
+		options.Root = "."
+	}
+	if len(options.Pattern) == 0 {
+		options.Pattern = am.Config.ReferencePattern
+	}
+	if len(options.Pattern) == 0 {
+		options.Pattern = DefaultReferencePattern
+	}
+	if len(options.Include) == 0 {
+		options.Include = am.Config.ReferenceInclude
+	}
+	if len(options.Exclude) == 0 {
+		options.Exclude = am.Config.ReferenceExclude
+	}
+
+	return options
+}
+
+// List the files below root: inside a git repository the tracked and
+// untracked, not ignored files; otherwise all files outside hidden directories.
+func listSourceFiles(ctx context.Context, root string) ([]string, error) {
+	logger := utils.Logger(ctx)
+	res := make([]string, 0)
+
+	if utils.IsGitRepository() {
+		out, err := utils.RunGit("ls-files", "--cached", "--others", "--exclude-standard", "--", root)
+		if err == nil {
+			for _, f := range strings.Split(out, "\n") {
+				if len(f) > 0 && utils.FileExists(f) {
+					res = append(res, filepath.FromSlash(f))
+				}
+			}
+			return res, nil
+		}
+		logger.Info("Could not list files with git, scanning all files", "err", err)
+	}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		res = append(res, p)
+		return nil
+	})
+
+	return res, err
+}
+
+// Check if the file (slash-separated path relative to the scan root) is to
+// be scanned: it has to match one of the include globs (if any), and none of
+// the exclude globs. Globs are matched against the path and the base name.
+func matchesScanGlobs(file string, include []string, exclude []string) bool {
+	matches := func(globs []string) bool {
+		for _, g := range globs {
+			if ok, _ := filepath.Match(g, file); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(g, filepath.Base(file)); ok {
+				return true
+			}
+			// 'dir/*' matches everything below dir
+			if strings.HasSuffix(g, "/*") && strings.HasPrefix(file, strings.TrimSuffix(g, "*")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return (len(include) == 0 || matches(include)) && !matches(exclude)
+}
+
+func scanFileForReferences(file string, r *regexp.Regexp) ([]AdrReference, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	res := make([]AdrReference, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		for _, m := range r.FindAllStringSubmatch(scanner.Text(), -1) {
+			res = append(res, AdrReference{File: file, Line: lineNo, Id: m[1]})
+		}
+	}
+
+	return res, nil
+}
+
+// Fill the ReferencedFrom of the ADR status entries (of the ADR log opened
+// by OpenAdrManager) with the source locations referencing them.
+func AddReferences(ctx context.Context, statuss []AdrStatus) error {
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return err
+	}
+	refs, err := am.ScanReferences(ctx, ReferenceScanOptions{})
+	if err != nil {
+		return err
+	}
+
+	locations := make(map[string][]string)
+	for _, ref := range refs {
+		if len(ref.AdrFilename) > 0 {
+			locations[ref.AdrFilename] = append(locations[ref.AdrFilename], ref.Location())
+		}
+	}
+	for i := range statuss {
+		statuss[i].ReferencedFrom = locations[statuss[i].Filename]
+	}
+
+	return nil
+}
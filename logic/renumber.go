@@ -0,0 +1,188 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Several ADRs using the same number in one number sequence, e.g. after
+// merging two branches which both added an ADR.
+type DuplicateIndex struct {
+	Index int
+	// Category with its own number sequence, or empty for the shared sequence.
+	Category string
+	// Files of the ADRs with this index (relative to the ADR folder), oldest first.
+	Files []string
+}
+
+// The ADRs which should get a new number, i.e. all but the oldest one.
+func (d DuplicateIndex) Newer() []string {
+	return d.Files[1:]
+}
+
+// Find all ADR numbers which are used by more than one ADR of the same
+// number sequence. The files of each duplicate are sorted by age, which is
+// determined by the creation commit if available, otherwise by the date of
+// the first status entry.
+func (am AdrManager) FindDuplicateIndexes(ctx context.Context) ([]DuplicateIndex, error) {
+	files, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type sequenceKey struct {
+		category string
+		index    int
+	}
+	byKey := make(map[sequenceKey][]string)
+	keys := make([]sequenceKey, 0)
+	for _, f := range files {
+		index, err := am.ExtractAdrIndexFromFile(f)
+		if err != nil {
+			continue
+		}
+		category := data.CategoryOfAdrFile(f)
+		if _, _, own := am.Config.NumberingOfCategory(category); !own {
+			category = ""
+		}
+		key := sequenceKey{category: category, index: index}
+		if _, known := byKey[key]; !known {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], f)
+	}
+
+	useGit := utils.IsGitRepository()
+	res := make([]DuplicateIndex, 0)
+	for _, key := range keys {
+		if len(byKey[key]) < 2 {
+			continue
+		}
+		dupFiles := byKey[key]
+		created := make(map[string]time.Time)
+		for _, f := range dupFiles {
+			created[f] = am.creationTime(ctx, f, useGit)
+		}
+		sort.SliceStable(dupFiles, func(i, j int) bool {
+			if created[dupFiles[i]].Equal(created[dupFiles[j]]) {
+				return dupFiles[i] < dupFiles[j]
+			}
+			return created[dupFiles[i]].Before(created[dupFiles[j]])
+		})
+		res = append(res, DuplicateIndex{Index: key.index, Category: key.category, Files: dupFiles})
+	}
+
+	return res, nil
+}
+
+// Plan giving the ADR in file filename (relative to the ADR folder) the
+// number index of its number sequence: its heading is changed, and the file
+// renamed accordingly.
+//
+// Returns the rename and the changed content of the ADR.
+func (am AdrManager) planRenumbering(ctx context.Context, filename string, index int) (FileRename, string, error) {
+	info, err := data.LoadAdrInfo(ctx, am.AdrDirectory(), filename)
+	if err != nil {
+		return FileRename{}, "", err
+	}
+	content, err := utils.ReadFile(info.RelativePath)
+	if err != nil {
+		return FileRename{}, "", err
+	}
+
+	info.Index = index
+	info.Id = am.createIndexForCategory(ctx, info.Category, index)
+	updated, err := data.WithAdrHeading(string(content), info.Id, info.Title)
+	if err != nil {
+		return FileRename{}, "", errors.New(fmt.Sprintf("Could not change heading of '%s': %v", info.RelativePath, err))
+	}
+
+	return FileRename{From: info.RelativePath, To: filepath.Join(am.AdrDirectory(), am.expectedFilename(ctx, info))}, updated, nil
+}
+
+// Renumber all newer ADRs of all duplicate ADR numbers: each gets the next
+// free number of its number sequence, links to it in other ADRs and the
+// configured doc folders are updated, and the TOC is regenerated. If one of
+// the changes fails, all are reverted.
+//
+// Returns the renamed files (paths including the ADR folder) and all
+// changed files, or an error.
+func RenumberDuplicates(ctx context.Context) (UpdateResult, error) {
+	logger := utils.Logger(ctx)
+	res := UpdateResult{Renames: make([]FileRename, 0), Changed: make([]string, 0), Changes: make([]FileChange, 0)}
+
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+	duplicates, err := am.FindDuplicateIndexes(ctx)
+	if err != nil {
+		return res, err
+	}
+	if len(duplicates) == 0 {
+		return res, nil
+	}
+
+	// the renumbered ADRs are not renamed before all are planned, so the
+	// next free number of each sequence is counted here
+	nextIndex := make(map[string]int)
+	contents := make(map[string]string)
+	for _, d := range duplicates {
+		if _, known := nextIndex[d.Category]; !known {
+			nextIndex[d.Category] = am.getNewIndex(ctx, d.Category)
+		}
+		for _, f := range d.Newer() {
+			rename, updated, err := am.planRenumbering(ctx, f, nextIndex[d.Category])
+			if err != nil {
+				logger.Debug("Could not renumber ADR", "file", f, "err", err)
+				return res, err
+			}
+			nextIndex[d.Category]++
+			res.Renames = append(res.Renames, rename)
+			contents[rename.From] = updated
+		}
+	}
+
+	filenames, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		return res, err
+	}
+	res.Changes = am.planLinkUpdates(ctx, filenames, res.Renames, contents, false)
+
+	return am.applyAndRegenerateToc(ctx, res)
+}
+
+func (am AdrManager) creationTime(ctx context.Context, filename string, useGit bool) time.Time {
+	adrPath := filepath.Join(am.AdrDirectory(), filename)
+	if useGit {
+		if commit, err := utils.GitCreationCommitOfFile(adrPath); err == nil {
+			return commit.Date
+		}
+	}
+
+	status, err := data.ReadStatusEntries(ctx, adrPath)
+	if err == nil && len(status) > 0 {
+		if date, err := time.Parse("2006-01-02", status[0].Date); err == nil {
+			return date
+		}
+	}
+
+	// not committed and without status: treat as newest
+	return time.Now()
+}
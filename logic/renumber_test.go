@@ -0,0 +1,73 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Of two ADRs with the same number, the one committed later is the newer
+// one, also if it was created from the template (whose commit is older;
+// the ADRs are committed in the future to be newer than the template).
+func TestFindDuplicateIndexesByCreationCommit(t *testing.T) {
+	am := setupGitAdrLog(t)
+	ctx := context.Background()
+
+	fromTemplate, err := am.AddAdr(ctx, "Use Kafka", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := filepath.Join(am.AdrDirectory(), "0001-use-pulsar.md")
+	if err := os.WriteFile(written, []byte("# 1. Use Pulsar\n\n## Status\n\n2023-07-01 Proposed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commitAs(t, "Alice", "2030-07-01T12:00:00Z", "propose pulsar", written)
+	commitAs(t, "Bob", "2030-07-02T12:00:00Z", "propose kafka", fromTemplate)
+
+	duplicates, err := am.FindDuplicateIndexes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(duplicates) != 1 {
+		t.Fatalf("expected one duplicate number, got %v", duplicates)
+	}
+	if newer := duplicates[0].Newer(); len(newer) != 1 || newer[0] != "0001-use-kafka.md" {
+		t.Errorf("expected 0001-use-kafka.md to be renumbered, got %v", newer)
+	}
+}
+
+// The newer ADR gets the next free number, and links to it are rewritten.
+func TestRenumberDuplicatesUpdatesLinks(t *testing.T) {
+	am := setupGitAdrLog(t)
+	ctx := context.Background()
+
+	writeTestAdrs(t, am, map[string]string{
+		"0001-use-pulsar.md": "# 1. Use Pulsar\n\n## Status\n\n2023-07-01 Proposed\n",
+		"0001-use-kafka.md":  "# 1. Use Kafka\n\n## Status\n\n2023-07-02 Proposed\n",
+		"0002-use-avro.md":   "# 2. Use Avro\n\n## Status\n\n2023-07-03 Proposed\n\nSerializes the events of [Kafka](0001-use-kafka.md).\n",
+	})
+
+	result, err := RenumberDuplicates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Renames) != 1 || filepath.Base(result.Renames[0].To) != "0003-use-kafka.md" {
+		t.Fatalf("expected 0001-use-kafka.md to be renumbered to 0003, got %v", result.Renames)
+	}
+	renumbered, err := os.ReadFile(filepath.Join(am.AdrDirectory(), "0003-use-kafka.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(renumbered), "# 0003. Use Kafka\n") {
+		t.Errorf("expected heading of renumbered ADR to be changed, got %q", renumbered)
+	}
+	linking, err := os.ReadFile(filepath.Join(am.AdrDirectory(), "0002-use-avro.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(linking), "[Kafka](0003-use-kafka.md)") {
+		t.Errorf("expected link to renumbered ADR to be rewritten, got %q", linking)
+	}
+}
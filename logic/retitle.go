@@ -0,0 +1,92 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Change the title of the ADR with the given id in one step: its heading is
+// rewritten, the file renamed to fit to the new title, links to it in other
+// ADRs and the configured doc folders are updated, and the TOC is
+// regenerated. If note is set, a status entry noting the title change (with
+// the ADR's current status) is added. If one of the changes fails, all are
+// reverted.
+//
+// Returns the changes, or an error.
+func RetitleAdr(ctx context.Context, adrId string, newTitle string, note bool) (UpdateResult, error) {
+	res := UpdateResult{Renames: make([]FileRename, 0), Changed: make([]string, 0), Changes: make([]FileChange, 0)}
+
+	newTitle = strings.TrimSpace(newTitle)
+	if len(newTitle) == 0 {
+		return res, errors.New("The new title must not be empty")
+	}
+
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	unlock, err := am.Lock(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer unlock()
+	filename, err := am.GetAdrFilenameById(ctx, adrId)
+	if err != nil {
+		return res, err
+	}
+	adrInfos, err := data.LoadAdrInfo(ctx, am.AdrDirectory(), filename)
+	if err != nil {
+		return res, err
+	}
+	adrPath := adrInfos.RelativePath
+	content, err := utils.ReadFile(adrPath)
+	if err != nil {
+		return res, err
+	}
+
+	updated, err := data.WithAdrHeading(string(content), adrInfos.Id, newTitle)
+	if err != nil {
+		return res, errors.New(fmt.Sprintf("Could not change heading of '%s': %v", adrPath, err))
+	}
+	if note {
+		status, err := data.ReadStatusEntries(ctx, adrPath)
+		if err != nil || len(status) == 0 {
+			return res, errors.New(fmt.Sprintf("Could not read current status of '%s'", adrPath))
+		}
+		updated, err = data.WithStatusEntry(updated, status[len(status)-1].Status, fmt.Sprintf("(title changed from '%s')", adrInfos.Title))
+		if err != nil {
+			return res, errors.New(fmt.Sprintf("Could not add status entry to '%s': %v", adrPath, err))
+		}
+	}
+
+	oldTitle := adrInfos.Title
+	adrInfos.Title = newTitle
+	newFilename := am.expectedFilename(ctx, adrInfos)
+	if newFilename != filename {
+		res.Renames = append(res.Renames, FileRename{From: adrPath, To: filepath.Join(am.AdrDirectory(), newFilename)})
+	}
+
+	filenames, err := am.GetAllAdrFileNamesIncludingArchive(ctx)
+	if err != nil {
+		return res, err
+	}
+	res.Changes = am.planLinkUpdates(ctx, filenames, res.Renames, map[string]string{adrPath: updated}, false)
+
+	// toc, generated from the files before the change
+	label := am.tocLabel(adrInfos)
+	toc := strings.ReplaceAll(am.GenerateToc(ctx),
+		"* ["+label+". "+oldTitle+"]("+adrPath+")",
+		"* ["+label+". "+newTitle+"]("+filepath.Join(am.AdrDirectory(), newFilename)+")")
+
+	return am.completeUpdate(ctx, res, toc)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// Metadata key of the tags of an ADR, a line like 'Tags: security, api'
+// below its title.
+const TagsMetadataKey = "Tags"
+
+// A range of ADR indexes, including both ends.
+type IndexRange struct {
+	From int
+	To   int
+}
+
+// Selection of ADRs by index, last status, date of the last status change
+// and tags; empty criteria select all ADRs.
+type AdrSelection struct {
+	Indexes []IndexRange
+	// Status the ADRs have currently (case-insensitive), one of them.
+	Status []string
+	// Date (2006-01-02) on or after which the last status change happened.
+	Since string
+	// Tags of which the ADRs have at least one (case-insensitive).
+	Tags []string
+}
+
+// Parse a list of index ranges like '1-3,7,10-': single indexes, ranges,
+// and ranges open at one end.
+func ParseIndexRanges(ranges string) ([]IndexRange, error) {
+	res := make([]IndexRange, 0)
+	for _, part := range strings.Split(ranges, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		r := IndexRange{From: 0, To: math.MaxInt}
+		var err error
+		if len(from) > 0 {
+			r.From, err = strconv.Atoi(strings.TrimSpace(from))
+		}
+		if err == nil && len(to) > 0 {
+			r.To, err = strconv.Atoi(strings.TrimSpace(to))
+		}
+		if !isRange {
+			r.To = r.From
+		}
+		if err != nil || (isRange && len(from) == 0 && len(to) == 0) || r.From > r.To {
+			return nil, errors.New(fmt.Sprintf("Invalid index range '%s', must be like '4', '1-3', '10-' or '-5'", part))
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// Check the selection's criteria for validity, e.g. the format of the date.
+func (selection AdrSelection) Validate() error {
+	if len(selection.Since) > 0 {
+		if _, err := time.Parse("2006-01-02", selection.Since); err != nil {
+			return errors.New(fmt.Sprintf("Invalid date '%s', must be like '2006-01-02'", selection.Since))
+		}
+	}
+
+	return nil
+}
+
+// Get the ADRs matching all criteria of the selection.
+func SelectAdrs(ctx context.Context, entries []AdrStatus, selection AdrSelection) []AdrStatus {
+	logger := utils.Logger(ctx)
+	res := make([]AdrStatus, 0)
+	for _, e := range entries {
+		if selection.matches(ctx, e) {
+			res = append(res, e)
+		}
+	}
+	logger.Debug("Selected ADRs", "count", len(res), "of", len(entries))
+
+	return res
+}
+
+func (selection AdrSelection) matches(ctx context.Context, e AdrStatus) bool {
+	if len(selection.Indexes) > 0 {
+		inRange := false
+		for _, r := range selection.Indexes {
+			inRange = inRange || (r.From <= e.Index && e.Index <= r.To)
+		}
+		if !inRange {
+			return false
+		}
+	}
+	if len(selection.Status) > 0 {
+		hasStatus := false
+		for _, s := range selection.Status {
+			hasStatus = hasStatus || strings.EqualFold(s, e.LastStatus)
+		}
+		if !hasStatus {
+			return false
+		}
+	}
+	if len(selection.Since) > 0 {
+		date, err := time.Parse("2006-01-02", e.LastModified)
+		since, _ := time.Parse("2006-01-02", selection.Since)
+		if err != nil || date.Before(since) {
+			return false
+		}
+	}
+	if len(selection.Tags) > 0 {
+		hasTag := false
+		for _, tag := range GetAdrTags(ctx, e) {
+			for _, t := range selection.Tags {
+				hasTag = hasTag || strings.EqualFold(t, tag)
+			}
+		}
+		if !hasTag {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get the tags of an ADR, from its metadata line 'Tags: ...' (tags
+// separated by commas).
+func GetAdrTags(ctx context.Context, e AdrStatus) []string {
+	logger := utils.Logger(ctx)
+	content, err := utils.ReadFile(e.Path)
+	if err != nil {
+		logger.Warn("Could not read ADR", "file", e.Path, "err", err)
+		return nil
+	}
+	doc, _ := utils.ParseMarkdown(content)
+	preamble, _ := doc.SplitSections(2)
+
+	return tagsOfMetadata(metadataOfPreamble(preamble.Markdown))
+}
+
+// Get the tags from the metadata of an ADR.
+func tagsOfMetadata(metadata map[string]string) []string {
+	res := make([]string, 0)
+	for key, value := range metadata {
+		if !strings.EqualFold(key, TagsMetadataKey) {
+			continue
+		}
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); len(tag) > 0 {
+				res = append(res, tag)
+			}
+		}
+	}
+
+	return res
+}
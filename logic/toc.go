@@ -0,0 +1,139 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const tocHeader = "# Architecture Decision Records\n\n"
+
+// Section of the TOC listing the archived ADRs, always the last one.
+const tocArchiveSection = "Archived"
+
+// One ADR in the TOC, with its nested lines (e.g. references to other ADR logs).
+type tocEntry struct {
+	Path   string
+	Label  string
+	Line   string
+	Nested []string
+}
+
+// TOC entries by category, the empty category holding the ADRs without category.
+type tocSections map[string][]tocEntry
+
+// Regex for a TOC entry line: label, title and path of the ADR.
+var tocEntryRegex = regexp.MustCompile(`^\* \[([^ \]]+)\. .*\]\((.*)\)$`)
+
+// Render the TOC sections as markdown: ADRs without category first, then
+// one section per category, and the archived ADRs last.
+func renderToc(sections tocSections) string {
+	var sb strings.Builder
+
+	// header
+	sb.WriteString(tocHeader)
+
+	// body
+	categories := make([]string, 0)
+	for category := range sections {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if (categories[i] == tocArchiveSection) != (categories[j] == tocArchiveSection) {
+			return categories[j] == tocArchiveSection
+		}
+		return categories[i] < categories[j]
+	})
+	for _, category := range categories {
+		if len(category) > 0 {
+			sb.WriteString("\n\n## " + category + "\n")
+		}
+		for _, entry := range sections[category] {
+			sb.WriteString("\n" + entry.Line)
+			for _, nested := range entry.Nested {
+				sb.WriteString("\n" + nested)
+			}
+		}
+	}
+
+	// footer
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// Parse a TOC as rendered by renderToc back into its sections. Lines not
+// belonging to an ADR entry are dropped.
+func parseToc(content string) tocSections {
+	sections := make(tocSections)
+	category := ""
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			category = strings.TrimSpace(strings.TrimPrefix(line, "## "))
+		case tocEntryRegex.MatchString(line):
+			m := tocEntryRegex.FindStringSubmatch(line)
+			sections[category] = append(sections[category], tocEntry{Path: m[2], Label: m[1], Line: line})
+		case strings.HasPrefix(line, "  ") && len(sections[category]) > 0:
+			entries := sections[category]
+			entries[len(entries)-1].Nested = append(entries[len(entries)-1].Nested, line)
+		}
+	}
+
+	return sections
+}
+
+// Merge three versions of a TOC (as written by GenerateToc) on the level of
+// ADR entries instead of text lines: an entry is kept if it is contained in
+// both versions, or if it was added (i.e. is not contained in the base) in
+// one of them. So both additions and removals (including renames) of either
+// side are kept.
+//
+// Returns the merged TOC, and the labels which are used by several
+// different ADRs in the merged TOC, i.e. duplicate ADR numbers.
+func MergeToc(base string, current string, other string) (string, []string) {
+	baseSections := parseToc(base)
+	currentSections := parseToc(current)
+	otherSections := parseToc(other)
+
+	contained := func(sections tocSections, category string, path string) bool {
+		for _, e := range sections[category] {
+			if e.Path == path {
+				return true
+			}
+		}
+		return false
+	}
+
+	merged := make(tocSections)
+	duplicates := make([]string, 0)
+	for _, side := range []tocSections{currentSections, otherSections} {
+		for category, entries := range side {
+			for _, e := range entries {
+				inBase := contained(baseSections, category, e.Path)
+				inCurrent := contained(currentSections, category, e.Path)
+				inOther := contained(otherSections, category, e.Path)
+				if contained(merged, category, e.Path) || !((inCurrent && inOther) || !inBase) {
+					continue
+				}
+				merged[category] = append(merged[category], e)
+			}
+		}
+	}
+
+	for category, entries := range merged {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+		seen := make(map[string]bool)
+		for _, e := range entries {
+			if seen[e.Label] {
+				duplicates = append(duplicates, strings.TrimPrefix(category+"/"+e.Label, "/"))
+			}
+			seen[e.Label] = true
+		}
+	}
+
+	return renderToc(merged), duplicates
+}
@@ -0,0 +1,285 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dukemarty/adr-go/data"
+	"github.com/dukemarty/adr-go/utils"
+)
+
+// A problem found by the validation of an ADR repository.
+type ValidationIssue struct {
+	// File with the problem, including the ADR folder.
+	File string
+	// Line of the problem in the file, 0 if not related to a line.
+	Line    int
+	Message string
+}
+
+func (issue ValidationIssue) String() string {
+	if issue.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", issue.File, issue.Line, issue.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", issue.File, issue.Message)
+}
+
+// Regex for a status line: date and status, optionally followed by a note.
+var statusLineRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})\s+(\S+)(\s.*)?$`)
+
+// Reads the content of a file (path including the ADR folder) to validate,
+// e.g. from the working tree or from the git index.
+type contentReader func(path string) ([]byte, error)
+
+// Validate the ADR files (relative to the ADR folder) of the repository:
+// heading and filename must fit to each other, the status entries must be
+// parseable and have known status. If files is empty, all ADRs are
+// validated. Additionally, the TOC is checked to be up-to-date.
+//
+// Returns the found issues, or an error if the validation could not be done.
+func (am AdrManager) Validate(ctx context.Context, files []string) ([]ValidationIssue, error) {
+	var err error
+	if len(files) == 0 {
+		files, err = am.GetAllAdrFileNames(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res := make([]ValidationIssue, 0)
+	for _, f := range files {
+		res = append(res, am.ValidateAdr(ctx, f)...)
+	}
+	res = append(res, am.ValidateToc(ctx)...)
+
+	return res, nil
+}
+
+// Validate a single ADR file (relative to the ADR folder).
+func (am AdrManager) ValidateAdr(ctx context.Context, filename string) []ValidationIssue {
+	return am.validateAdr(ctx, filename, utils.ReadFile)
+}
+
+func (am AdrManager) validateAdr(ctx context.Context, filename string, read contentReader) []ValidationIssue {
+	adrPath := filepath.Join(am.AdrDirectory(), filename)
+	res := make([]ValidationIssue, 0)
+
+	content, err := read(adrPath)
+	if err != nil {
+		return append(res, ValidationIssue{File: adrPath, Message: fmt.Sprintf("can not be read: %v", err)})
+	}
+
+	// heading and filename
+	adrInfos, err := data.ParseAdrInfo(ctx, am.AdrDirectory(), filename, content)
+	if err != nil {
+		return append(res, ValidationIssue{File: adrPath, Line: 1, Message: fmt.Sprintf("heading can not be parsed: %v", err)})
+	}
+	expected := am.expectedFilename(ctx, adrInfos)
+	if expected != filename {
+		res = append(res, ValidationIssue{File: adrPath, Line: 1, Message: fmt.Sprintf("filename does not fit to heading '%s. %s', expected '%s' (fix with 'adr-go update')", adrInfos.Id, adrInfos.Title, filepath.Base(expected))})
+	}
+
+	// status entries
+	inStatus, statusFound, entries := false, false, 0
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			inStatus = strings.EqualFold(strings.TrimSpace(strings.TrimLeft(trimmed, "#")), "Status")
+			statusFound = statusFound || inStatus
+			continue
+		}
+		if !inStatus || len(trimmed) == 0 {
+			continue
+		}
+		entries++
+		m := statusLineRegex.FindStringSubmatch(trimmed)
+		if m == nil {
+			res = append(res, ValidationIssue{File: adrPath, Line: i + 1, Message: fmt.Sprintf("status entry '%s' is not of the form '<YYYY-MM-DD> <status> [<note>]'", trimmed)})
+		} else if !data.IsSupportedStatus(m[2]) {
+			res = append(res, ValidationIssue{File: adrPath, Line: i + 1, Message: fmt.Sprintf("unknown status '%s', allowed: %v", m[2], data.SupportedStatus)})
+		}
+	}
+	if !statusFound {
+		res = append(res, ValidationIssue{File: adrPath, Message: "no 'Status' section"})
+	} else if entries == 0 {
+		res = append(res, ValidationIssue{File: adrPath, Message: "no status entry in 'Status' section"})
+	}
+
+	return res
+}
+
+// Check that the TOC (README) is up-to-date with the ADRs.
+func (am AdrManager) ValidateToc(ctx context.Context) []ValidationIssue {
+	readmePath := filepath.Join(am.AdrDirectory(), "README.md")
+	content, err := utils.ReadFile(readmePath)
+	if err != nil {
+		return []ValidationIssue{{File: readmePath, Message: "TOC is missing (create with 'adr-go update')"}}
+	}
+
+	if string(content) != am.GenerateToc(ctx) {
+		return []ValidationIssue{{File: readmePath, Message: "TOC is not up-to-date (fix with 'adr-go update')"}}
+	}
+
+	return []ValidationIssue{}
+}
+
+// Get the ADR files (relative to the ADR folder) among the files staged in
+// git for the next commit.
+func (am AdrManager) GetStagedAdrFileNames(ctx context.Context) ([]string, error) {
+	out, err := utils.RunGit("diff", "--cached", "--name-only", "--diff-filter=ACMR")
+	if err != nil {
+		return nil, err
+	}
+	root, err := utils.RunGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	adrDir, err := filepath.Abs(am.AdrDirectory())
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := am.GetAllAdrFileNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	isAdr := make(map[string]bool)
+	for _, f := range all {
+		isAdr[f] = true
+	}
+
+	res := make([]string, 0)
+	for _, staged := range strings.Split(strings.TrimSpace(out), "\n") {
+		if len(staged) == 0 {
+			continue
+		}
+		rel, err := filepath.Rel(adrDir, filepath.Join(strings.TrimSpace(root), staged))
+		if err != nil || !isAdr[rel] {
+			continue
+		}
+		res = append(res, rel)
+	}
+
+	return res, nil
+}
+
+// Validate the ADR repository; if staged is set, only the ADRs staged in git
+// are validated, with their content as staged. The TOC is always checked in
+// the working tree, as it is generated from all ADRs.
+func ValidateAdrRepository(ctx context.Context, staged bool) ([]ValidationIssue, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		logger.Debug("Error opening ADR management", "err", err)
+		return nil, err
+	}
+
+	if !staged {
+		return am.Validate(ctx, []string{})
+	}
+
+	files, err := am.GetStagedAdrFileNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		logger.Debug("No ADRs staged, only validating TOC")
+		return am.ValidateToc(ctx), nil
+	}
+
+	res := make([]ValidationIssue, 0)
+	for _, f := range files {
+		res = append(res, am.validateAdr(ctx, f, utils.GitStagedContent)...)
+	}
+
+	return append(res, am.ValidateToc(ctx)...), nil
+}
+
+// Fix the filenames of ADRs and the TOC, like the update command does, and
+// stage the changes in git, so that they become part of the next commit.
+func FixAndStageAdrRepository(ctx context.Context) (UpdateResult, error) {
+	logger := utils.Logger(ctx)
+	result, err := UpdateAdrRepository(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	files := append([]string{}, result.Changed...)
+	for _, r := range result.Renames {
+		files = append(files, r.From)
+	}
+	if utils.IsDryRun() {
+		return result, nil
+	}
+	_, err = utils.RunGit(append([]string{"add", "-A", "--"}, files...)...)
+	if err != nil {
+		logger.Debug("Could not stage fixed files", "err", err)
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Install git hooks validating the ADR repository: a pre-commit hook
+// validating the staged ADRs (and, if fix is set, fixing filenames and TOC
+// first), and a pre-push hook validating all ADRs.
+//
+// Takes the command line of the adr-go executable to use. Returns the
+// written hook files, or an error.
+func InstallValidationHooks(ctx context.Context, executable string, fix bool) ([]string, error) {
+	logger := utils.Logger(ctx)
+	am, err := OpenAdrManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsGitRepository() {
+		return nil, errors.New("Not inside a git repository, hooks can not be installed.")
+	}
+
+	root, err := utils.RunGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	out, err := utils.RunGit("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return nil, err
+	}
+	hooksDir := strings.TrimSpace(out)
+	if err := utils.MkdirAll(hooksDir); err != nil {
+		return nil, err
+	}
+	baseDir, _ := filepath.Abs(am.BaseDir)
+	relBaseDir, _ := filepath.Rel(strings.TrimSpace(root), baseDir)
+
+	preCommit := "validate --staged"
+	if fix {
+		preCommit += " --fix"
+	}
+	hooks := map[string]string{"pre-commit": preCommit, "pre-push": "validate"}
+
+	res := make([]string, 0)
+	for _, name := range []string{"pre-commit", "pre-push"} {
+		hookFile := filepath.Join(hooksDir, name)
+		hookLine := fmt.Sprintf("(cd %s && %s %s) || exit 1", shellQuote(filepath.ToSlash(relBaseDir)), shellQuote(executable), hooks[name])
+		appended, err := insertHookLineIfMissing(hookFile, hookLine)
+		if err != nil {
+			logger.Debug("Could not update file", "file", hookFile, "err", err)
+			return res, err
+		}
+		if appended {
+			os.Chmod(hookFile, 0755)
+			res = append(res, hookFile)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,67 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// With staged, the ADRs are validated as staged in git, not as in the
+// working tree.
+func TestValidateStagedAdrContent(t *testing.T) {
+	am := setupGitAdrLog(t)
+	ctx := context.Background()
+
+	adrPath := filepath.Join(am.AdrDirectory(), "0001-use-kafka.md")
+	writeTestAdrs(t, am, map[string]string{"0001-use-kafka.md": "# 0001. Use Kafka\n\n## Status\n\n2023-07-01 Bogus\n"})
+	mustRunGit(t, "add", "--", adrPath)
+	writeTestAdrs(t, am, map[string]string{"0001-use-kafka.md": "# 0001. Use Kafka\n\n## Status\n\n2023-07-01 Proposed\n"})
+	if err := os.WriteFile(filepath.Join(am.AdrDirectory(), "README.md"), []byte(am.GenerateToc(ctx)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := ValidateAdrRepository(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("expected no issues in the working tree, got %v", issues)
+	}
+
+	issues, err = ValidateAdrRepository(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "unknown status 'Bogus'") {
+		t.Errorf("expected the unknown status of the staged ADR, got %v", issues)
+	}
+}
+
+// The hook line is inserted right after the shebang, so that it runs also
+// if the existing hook ends with 'exit 0'; it is not inserted twice.
+func TestInsertHookLineIfMissing(t *testing.T) {
+	hookFile := filepath.Join(t.TempDir(), "pre-commit")
+	if err := os.WriteFile(hookFile, []byte("#!/bin/sh\nrun-linter\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []bool{true, false} {
+		inserted, err := insertHookLineIfMissing(hookFile, "adr-go validate || exit 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inserted != expected {
+			t.Errorf("call %d: expected inserted to be %v", i+1, expected)
+		}
+	}
+
+	content, err := os.ReadFile(hookFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "#!/bin/sh\nadr-go validate || exit 1\nrun-linter\nexit 0\n" {
+		t.Errorf("unexpected hook content %q", content)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Number of unchanged lines shown around each change of a unified diff.
+const diffContextLines = 3
+
+// Create a unified diff (like 'diff -u') of two texts, with fromName and
+// toName as file names in the header. Returns an empty string if the texts
+// are equal.
+func UnifiedDiff(fromName string, toName string, before string, after string) string {
+	if before == after {
+		return ""
+	}
+	a := splitLines(before)
+	b := splitLines(after)
+
+	// longest common subsequence of lines, lcs[i][j] for a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// edit script: ' ', '-' or '+' for each line, with its line numbers
+	type edit struct {
+		op   byte
+		text string
+		i, j int
+	}
+	edits := make([]edit, 0)
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+	for start := 0; start < len(edits); {
+		// find next change, and the end of its hunk
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].op != ' ' {
+				end = k
+			} else if k-end > 2*diffContextLines {
+				break
+			}
+		}
+		hunkEnd := end + diffContextLines + 1
+		if hunkEnd > len(edits) {
+			hunkEnd = len(edits)
+		}
+
+		countA, countB := 0, 0
+		for _, e := range edits[hunkStart:hunkEnd] {
+			if e.op != '+' {
+				countA++
+			}
+			if e.op != '-' {
+				countB++
+			}
+		}
+		// like diff -u, an empty range starts at the line before it
+		startA, startB := edits[hunkStart].i+1, edits[hunkStart].j+1
+		if countA == 0 {
+			startA--
+		}
+		if countB == 0 {
+			startB--
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB))
+		for _, e := range edits[hunkStart:hunkEnd] {
+			sb.WriteString(string(e.op) + e.text + "\n")
+		}
+		start = hunkEnd
+	}
+
+	return sb.String()
+}
+
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
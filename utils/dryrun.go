@@ -0,0 +1,224 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A rename of a file in the change set of a dry run.
+type pendingRename struct {
+	from string
+	to   string
+}
+
+// The change set of a dry run: instead of changing files, WriteFile,
+// CreateFile, RenameFile and RemoveFile record the changes here, and
+// ReadFile, FileExists and PendingFiles take them into account.
+var dryRun changeSet
+
+type changeSet struct {
+	sync.Mutex
+	active bool
+	// Content of the changed and removed files by absolute path.
+	files   map[string][]byte
+	removed map[string]bool
+	// Changed files in order of their first change.
+	order   []string
+	renames []pendingRename
+}
+
+// Start a dry run: all following changes of files are only recorded in
+// the change set, see DescribeDryRun.
+func StartDryRun() {
+	dryRun.Lock()
+	defer dryRun.Unlock()
+	dryRun.active = true
+	dryRun.files = make(map[string][]byte)
+	dryRun.removed = make(map[string]bool)
+	dryRun.order = make([]string, 0)
+	dryRun.renames = make([]pendingRename, 0)
+}
+
+// Check if a dry run is active, i.e. files must not be changed.
+func IsDryRun() bool {
+	dryRun.Lock()
+	defer dryRun.Unlock()
+	return dryRun.active
+}
+
+// Read a file, with the content it has after the changes of a dry run.
+func ReadFile(filename string) ([]byte, error) {
+	dryRun.Lock()
+	content, removed, changed := dryRun.lookup(filename)
+	dryRun.Unlock()
+	if removed && changed {
+		return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+	}
+	if changed {
+		return bytes.Clone(content), nil
+	}
+
+	return os.ReadFile(filename)
+}
+
+// Get the files created and removed (as absolute paths) below the directory
+// by the changes of a dry run.
+func PendingFiles(directory string) ([]string, map[string]bool) {
+	created := make([]string, 0)
+	removed := make(map[string]bool)
+	dir, err := filepath.Abs(directory)
+	if err != nil {
+		return created, removed
+	}
+
+	dryRun.Lock()
+	defer dryRun.Unlock()
+	for _, path := range dryRun.order {
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			continue
+		}
+		if dryRun.removed[path] {
+			removed[path] = true
+		} else if !fileExistsOnDisk(path) {
+			created = append(created, path)
+		}
+	}
+	sort.Strings(created)
+
+	return created, removed
+}
+
+// Describe all changes of the dry run: created, renamed and removed files
+// by name, content changes as unified diff. Paths are relative to the
+// working directory.
+func DescribeDryRun() string {
+	dryRun.Lock()
+	defer dryRun.Unlock()
+
+	var res strings.Builder
+	described := make(map[string]bool)
+	for _, r := range dryRun.renames {
+		described[r.from] = true
+		described[r.to] = true
+		before, _ := os.ReadFile(r.from)
+		after := dryRun.files[r.to]
+		res.WriteString(fmt.Sprintf("rename %s -> %s\n", displayPath(r.from), displayPath(r.to)))
+		res.WriteString(UnifiedDiff("a/"+displayPath(r.from), "b/"+displayPath(r.to), string(before), string(after)))
+	}
+	for _, path := range dryRun.order {
+		if described[path] {
+			continue
+		}
+		existed := fileExistsOnDisk(path)
+		before, _ := os.ReadFile(path)
+		switch {
+		case dryRun.removed[path] && existed:
+			res.WriteString(fmt.Sprintf("delete %s\n", displayPath(path)))
+		case dryRun.removed[path]:
+		case !existed:
+			res.WriteString(fmt.Sprintf("create %s\n", displayPath(path)))
+			res.WriteString(UnifiedDiff("/dev/null", "b/"+displayPath(path), "", string(dryRun.files[path])))
+		default:
+			res.WriteString(UnifiedDiff("a/"+displayPath(path), "b/"+displayPath(path), string(before), string(dryRun.files[path])))
+		}
+	}
+
+	return res.String()
+}
+
+// Record writing the content to the file in the change set of the dry run.
+func stageWrite(filename string, content []byte) {
+	dryRun.Lock()
+	defer dryRun.Unlock()
+	path := dryRun.stage(filename)
+	dryRun.files[path] = bytes.Clone(content)
+	delete(dryRun.removed, path)
+}
+
+// Record renaming the file in the change set of the dry run.
+func stageRename(from string, to string) error {
+	content, err := ReadFile(from)
+	if err != nil {
+		return err
+	}
+
+	dryRun.Lock()
+	defer dryRun.Unlock()
+	fromPath := dryRun.stage(from)
+	toPath := dryRun.stage(to)
+	dryRun.files[toPath] = content
+	delete(dryRun.removed, toPath)
+	delete(dryRun.files, fromPath)
+	dryRun.removed[fromPath] = true
+
+	for i, r := range dryRun.renames {
+		if r.to == fromPath {
+			dryRun.renames[i].to = toPath
+			return nil
+		}
+	}
+	dryRun.renames = append(dryRun.renames, pendingRename{from: fromPath, to: toPath})
+
+	return nil
+}
+
+// Record removing the file in the change set of the dry run.
+func stageRemove(filename string) error {
+	if !FileExists(filename) {
+		return &os.PathError{Op: "remove", Path: filename, Err: os.ErrNotExist}
+	}
+
+	dryRun.Lock()
+	defer dryRun.Unlock()
+	path := dryRun.stage(filename)
+	delete(dryRun.files, path)
+	dryRun.removed[path] = true
+
+	return nil
+}
+
+// Get the absolute path of the file, adding it to the changed files.
+func (cs *changeSet) stage(filename string) string {
+	path, _ := filepath.Abs(filename)
+	if _, ok := cs.files[path]; !ok && !cs.removed[path] {
+		cs.order = append(cs.order, path)
+	}
+
+	return path
+}
+
+// Get the content of the file in the change set, and whether it is removed
+// or changed there at all.
+func (cs *changeSet) lookup(filename string) ([]byte, bool, bool) {
+	if !cs.active {
+		return nil, false, false
+	}
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, false, false
+	}
+	if cs.removed[path] {
+		return nil, true, true
+	}
+	content, ok := cs.files[path]
+
+	return content, false, ok
+}
+
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	return filepath.ToSlash(path)
+}
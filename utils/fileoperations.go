@@ -4,13 +4,26 @@ Copyright © 2023 Martin Loesch <development@martinloesch.net>
 package utils
 
 import (
+	"context"
 	"errors"
-	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 )
 
+// Check if the file exists, taking into account the changes of a dry run.
 func FileExists(filename string) bool {
+	dryRun.Lock()
+	_, removed, changed := dryRun.lookup(filename)
+	dryRun.Unlock()
+	if changed {
+		return !removed
+	}
+
+	return fileExistsOnDisk(filename)
+}
+
+func fileExistsOnDisk(filename string) bool {
 	if _, err := os.Stat(filename); err == nil {
 		// filename exists
 		return true
@@ -25,7 +38,8 @@ func FileExists(filename string) bool {
 	}
 }
 
-func EditFile(filename string, editor string, defaultEditor string, logger *log.Logger) {
+func EditFile(ctx context.Context, filename string, editor string, defaultEditor string) {
+	logger := Logger(ctx)
 	if len(editor) == 0 {
 		if len(defaultEditor) == 0 {
 			editor = os.Getenv("EDITOR")
@@ -34,16 +48,151 @@ func EditFile(filename string, editor string, defaultEditor string, logger *log.
 		}
 	}
 	if len(editor) > 0 {
-		logger.Printf("Opening '%s' with '%s'\n", filename, editor)
+		logger.Debug("Opening file in editor", "file", filename, "editor", editor)
 		cmd := exec.Command(editor, filename)
 		err := cmd.Start()
 		if err == nil {
 			cmd.Process.Release()
 		} else {
-			logger.Printf("Error when trying to start editor: %v\n", err)
+			logger.Warn("Error when trying to start editor", "err", err)
 		}
 	} else {
-		logger.Println("EDITOR environment variable not set, therefor ADR can not be opened.")
+		logger.Info("EDITOR environment variable not set, therefore ADR can not be opened")
+	}
+
+}
+
+// Write data to the file atomically: the data is written to a temporary
+// file in the same folder, which then replaces the file. The previous state
+// of the file is recorded in the journal, if one is active. In a dry run,
+// the write is only recorded in the change set.
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	if IsDryRun() {
+		stageWrite(filename, data)
+		return nil
+	}
+	err := recordInJournal(filename)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filename)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+	}
+
+	return err
+}
+
+// Create the directory and all missing parents, unless in a dry run.
+func MkdirAll(directory string) error {
+	if IsDryRun() {
+		return nil
+	}
+
+	return os.MkdirAll(directory, os.ModePerm)
+}
+
+// Rename a file, recording the previous state of both paths in the journal,
+// if one is active.
+func RenameFile(from string, to string) error {
+	if IsDryRun() {
+		return stageRename(from, to)
+	}
+	if err := recordInJournal(from); err != nil {
+		return err
+	}
+	if err := recordInJournal(to); err != nil {
+		return err
+	}
+
+	return os.Rename(from, to)
+}
+
+// Remove a file, recording its previous state in the journal, if one is active.
+func RemoveFile(filename string) error {
+	if IsDryRun() {
+		return stageRemove(filename)
+	}
+	if err := recordInJournal(filename); err != nil {
+		return err
+	}
+
+	return os.Remove(filename)
+}
+
+// Create a new file with the data, atomically and exclusively: if the file
+// already exists, it is left untouched and an error wrapping os.ErrExist is
+// returned. The creation is recorded in the journal, if one is active.
+func CreateFile(filename string, data []byte, perm os.FileMode) error {
+	if FileExists(filename) {
+		return &os.PathError{Op: "create", Path: filename, Err: os.ErrExist}
+	}
+	if IsDryRun() {
+		stageWrite(filename, data)
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, perm)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Unlike a rename, a hard link fails if the target exists. Filesystems
+	// without hard links get the content written to the exclusively created
+	// file instead.
+	err = os.Link(tmpName, filename)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		err = writeExclusive(filename, data, perm)
+	}
+	if err != nil {
+		return err
+	}
+
+	return recordCreationInJournal(filename)
+}
+
+func writeExclusive(filename string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
 	}
 
+	return err
 }
@@ -0,0 +1,222 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hash git uses for lines which are not committed yet.
+const UncommittedHash = "0000000000000000000000000000000000000000"
+
+type GitCommit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Subject string
+	// Message body of the commit, e.g. containing trailers.
+	Body string
+	// Patch of the commit, only filled if requested.
+	Patch string
+}
+
+type GitBlameLine struct {
+	Hash    string
+	Author  string
+	Date    time.Time
+	Summary string
+	Content string
+}
+
+// Run the local git binary with the given arguments in the working
+// directory, and return its (standard) output.
+func RunGit(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return stdout.String(), errors.New(fmt.Sprintf("git %s failed: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String())))
+	}
+
+	return stdout.String(), nil
+}
+
+// Check if the working directory is inside a git working tree.
+func IsGitRepository() bool {
+	out, err := RunGit("rev-parse", "--is-inside-work-tree")
+
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// Get the content of the file as staged in the git index, i.e. as it will
+// be committed.
+func GitStagedContent(file string) ([]byte, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil {
+		return nil, err
+	}
+
+	// the path is relative to the working directory only if it starts with './'
+	out, err := RunGit("show", ":./"+filepath.ToSlash(rel))
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(out), nil
+}
+
+// Get the commits which changed the file, newest first, following renames.
+// If withPatch is set, each commit's patch of the file is included.
+//
+// The history ends with the commit which added the file: git's --follow
+// detects copies as well, and would continue with the history of e.g. the
+// template a new ADR was created from.
+func GitLogOfFile(file string, withPatch bool) ([]GitCommit, error) {
+	creation, err := gitCreationHashOfFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"log", "--follow", gitLogFormat}
+	if withPatch {
+		args = append(args, "-p")
+	}
+	args = append(args, "--", file)
+
+	out, err := RunGit(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := parseGitLog(out)
+	for i, c := range commits {
+		if c.Hash == creation {
+			return commits[:i+1], nil
+		}
+	}
+
+	return commits, nil
+}
+
+// Get the hash of the commit which added the file, following renames but
+// not copies; empty if the file is not committed yet.
+func gitCreationHashOfFile(file string) (string, error) {
+	out, err := RunGit("log", "--follow", "--name-status", "--format=%x1e%H", "--", file)
+	if err != nil {
+		return "", err
+	}
+
+	for _, record := range strings.Split(out, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		for _, line := range lines[1:] {
+			// status 'A' for an added file, 'C<score>' for a detected copy
+			if strings.HasPrefix(line, "A\t") || strings.HasPrefix(line, "C") {
+				return lines[0], nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// Get all commits of the current branch, newest first, including their
+// message bodies.
+func GitLog() ([]GitCommit, error) {
+	out, err := RunGit("log", gitLogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGitLog(out), nil
+}
+
+// Get the commit which created the file, i.e. the oldest commit of its
+// history (see GitLogOfFile).
+func GitCreationCommitOfFile(file string) (GitCommit, error) {
+	commits, err := GitLogOfFile(file, false)
+	if err != nil {
+		return GitCommit{}, err
+	}
+	if len(commits) == 0 {
+		return GitCommit{}, errors.New(fmt.Sprintf("File '%s' is not committed yet", file))
+	}
+
+	return commits[len(commits)-1], nil
+}
+
+// Get the commit information for each line of the file.
+func GitBlame(file string) ([]GitBlameLine, error) {
+	out, err := RunGit("blame", "--line-porcelain", "--", file)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]GitBlameLine, 0)
+	var current GitBlameLine
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			current.Content = line[1:]
+			res = append(res, current)
+			current = GitBlameLine{}
+		case strings.HasPrefix(line, "author "):
+			current.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			seconds, _ := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64)
+			current.Date = time.Unix(seconds, 0)
+		case strings.HasPrefix(line, "summary "):
+			current.Summary = strings.TrimPrefix(line, "summary ")
+		case len(current.Hash) == 0 && len(line) >= 40:
+			current.Hash = strings.Fields(line)[0]
+		}
+	}
+
+	return res, nil
+}
+
+// Format of commits for parseGitLog: each commit starts with a record
+// separator, its fields are separated by unit separators.
+const gitLogFormat = "--format=%x1e%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1f%b%x1f"
+
+func parseGitLog(out string) []GitCommit {
+	res := make([]GitCommit, 0)
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(record, "\x1f", 7)
+		if len(fields) < 7 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[3])
+		res = append(res, GitCommit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Subject: fields[4],
+			Body:    strings.TrimSpace(fields[5]),
+			Patch:   strings.TrimSpace(fields[6]),
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,222 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Folder (in the project directory) for adr-go's internal data like the journal.
+const DataFolder = ".adr"
+
+// Subfolder of the data folder containing the journal, with one folder per
+// operation.
+const journalFolder = "journal"
+
+// Name of the file describing an operation in its journal folder.
+const journalEntryFilename = "operation.json"
+
+// Number of operations kept in the journal.
+const journalSize = 50
+
+// The previous state of a file changed by an operation.
+type JournalFile struct {
+	// Absolute path of the file.
+	Path    string `json:"path"`
+	Existed bool   `json:"existed"`
+	// Name of the copy of the previous content in the operation's journal
+	// folder, if the file existed.
+	Backup string `json:"backup,omitempty"`
+}
+
+// An operation recorded in the journal, with the previous state of all
+// files it changed.
+type JournalEntry struct {
+	// Id of the entry, i.e. the name of its folder; ordered by time.
+	Id        string        `json:"-"`
+	Operation string        `json:"operation"`
+	Time      time.Time     `json:"time"`
+	Files     []JournalFile `json:"files"`
+}
+
+// The journal the current operation is recorded in; no recording if the
+// directory is empty.
+var journal journalState
+
+type journalState struct {
+	sync.Mutex
+	directory string
+	operation string
+	entry     *JournalEntry
+}
+
+// Make git ignore the data folder in directory, by a .gitignore file in it.
+func IgnoreDataFolder(directory string) {
+	gitignore := filepath.Join(directory, ".gitignore")
+	if !FileExists(gitignore) {
+		os.WriteFile(gitignore, []byte("*\n"), 0644)
+	}
+}
+
+// Set the description of the current operation, e.g. the command line.
+func SetJournalOperation(operation string) {
+	journal.operation = operation
+}
+
+// Activate the journal in the directory (usually DataFolder of the
+// project), recording the changes of the current operation.
+func StartJournal(directory string) {
+	journal.Lock()
+	defer journal.Unlock()
+	journal.directory = directory
+	journal.entry = nil
+}
+
+// Stop recording changes in the journal.
+func StopJournal() {
+	journal.Lock()
+	defer journal.Unlock()
+	journal.directory = ""
+	journal.entry = nil
+}
+
+// Record the current state of the file in the journal entry of the current
+// operation, unless it is recorded already. The entry is created with the
+// first recorded file.
+func recordInJournal(filename string) error {
+	return recordStateInJournal(filename, true)
+}
+
+// Record that the file did not exist before the current operation, e.g.
+// after it was created.
+func recordCreationInJournal(filename string) error {
+	return recordStateInJournal(filename, false)
+}
+
+func recordStateInJournal(filename string, existing bool) error {
+	journal.Lock()
+	defer journal.Unlock()
+	if len(journal.directory) == 0 {
+		return nil
+	}
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+
+	if journal.entry == nil {
+		now := time.Now()
+		journal.entry = &JournalEntry{Id: now.UTC().Format("20060102T150405.000000000"), Operation: journal.operation, Time: now, Files: make([]JournalFile, 0)}
+		if err := os.MkdirAll(journal.entryDirectory(), os.ModePerm); err != nil {
+			return errors.New(fmt.Sprintf("Could not create journal entry: %v", err))
+		}
+		IgnoreDataFolder(journal.directory)
+		pruneJournal(journal.directory)
+	}
+	for _, f := range journal.entry.Files {
+		if f.Path == path {
+			return nil
+		}
+	}
+
+	recorded := JournalFile{Path: path}
+	if content, err := os.ReadFile(path); existing && err == nil {
+		recorded.Existed = true
+		recorded.Backup = strconv.Itoa(len(journal.entry.Files))
+		if err := os.WriteFile(filepath.Join(journal.entryDirectory(), recorded.Backup), content, 0644); err != nil {
+			return errors.New(fmt.Sprintf("Could not record '%s' in journal: %v", filename, err))
+		}
+	}
+	journal.entry.Files = append(journal.entry.Files, recorded)
+
+	content, _ := json.MarshalIndent(journal.entry, "", "  ")
+	return os.WriteFile(filepath.Join(journal.entryDirectory(), journalEntryFilename), content, 0644)
+}
+
+func (j *journalState) entryDirectory() string {
+	return filepath.Join(j.directory, journalFolder, j.entry.Id)
+}
+
+// Remove the oldest entries of the journal in the directory, keeping at most
+// journalSize entries (including the one currently written, which is not
+// read as it has no description yet).
+func pruneJournal(directory string) {
+	entries, err := ReadJournal(directory)
+	if err != nil || len(entries) < journalSize {
+		return
+	}
+	for _, e := range entries[:len(entries)-journalSize+1] {
+		os.RemoveAll(filepath.Join(directory, journalFolder, e.Id))
+	}
+}
+
+// Get all operations recorded in the journal in the directory, oldest first.
+func ReadJournal(directory string) ([]JournalEntry, error) {
+	res := make([]JournalEntry, 0)
+	folders, err := os.ReadDir(filepath.Join(directory, journalFolder))
+	if errors.Is(err, os.ErrNotExist) {
+		return res, nil
+	} else if err != nil {
+		return res, err
+	}
+
+	for _, f := range folders {
+		if !f.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(directory, journalFolder, f.Name(), journalEntryFilename))
+		if err != nil {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			return res, errors.New(fmt.Sprintf("Could not parse journal entry '%s': %v", f.Name(), err))
+		}
+		entry.Id = f.Name()
+		res = append(res, entry)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+
+	return res, nil
+}
+
+// Restore the state of all files before the operation and remove it from
+// the journal in the directory. Files are restored in reverse order of
+// recording, which is not recorded itself.
+func UndoJournalEntry(directory string, entry JournalEntry) error {
+	entryDir := filepath.Join(directory, journalFolder, entry.Id)
+	for i := len(entry.Files) - 1; i >= 0; i-- {
+		f := entry.Files[i]
+		if f.Existed {
+			content, err := os.ReadFile(filepath.Join(entryDir, f.Backup))
+			if err != nil {
+				return errors.New(fmt.Sprintf("Could not read backup of '%s': %v", f.Path, err))
+			}
+			if err := MkdirAll(filepath.Dir(f.Path)); err != nil {
+				return err
+			}
+			if err := WriteFile(f.Path, content, 0644); err != nil {
+				return errors.New(fmt.Sprintf("Could not restore '%s': %v", f.Path, err))
+			}
+		} else if FileExists(f.Path) {
+			if err := RemoveFile(f.Path); err != nil {
+				return errors.New(fmt.Sprintf("Could not remove '%s': %v", f.Path, err))
+			}
+		}
+	}
+
+	if IsDryRun() {
+		return nil
+	}
+
+	return os.RemoveAll(entryDir)
+}
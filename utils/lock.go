@@ -0,0 +1,122 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Age after which a lock file is considered stale, i.e. left behind by a
+// crashed process, and is removed.
+const staleLockAge = 10 * time.Minute
+
+// Locks held by this process, by lock file path; lock files only exclude
+// other processes, so goroutines are excluded by these mutexes.
+var processLocks = struct {
+	sync.Mutex
+	byPath map[string]*sync.Mutex
+}{byPath: make(map[string]*sync.Mutex)}
+
+// Acquire the advisory lock represented by the lock file, waiting for at
+// most timeout if it is held by another process or goroutine. The lock is
+// not reentrant.
+//
+// Returns a function releasing the lock, or an error if the lock could not
+// be acquired in time.
+func AcquireLock(lockFile string, timeout time.Duration) (func(), error) {
+	path, err := filepath.Abs(lockFile)
+	if err != nil {
+		return nil, err
+	}
+	processLocks.Lock()
+	mutex, ok := processLocks.byPath[path]
+	if !ok {
+		mutex = &sync.Mutex{}
+		processLocks.byPath[path] = mutex
+	}
+	processLocks.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for !mutex.TryLock() {
+		if time.Now().After(deadline) {
+			return nil, errors.New(fmt.Sprintf("Timeout waiting for lock '%s'", lockFile))
+		}
+		time.Sleep(time.Duration(5+rand.Intn(20)) * time.Millisecond)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		mutex.Unlock()
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			hostname, _ := os.Hostname()
+			fmt.Fprintf(f, "pid %d on %s since %s\n", os.Getpid(), hostname, time.Now().Format(time.RFC3339))
+			f.Close()
+			return func() {
+				os.Remove(path)
+				mutex.Unlock()
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			mutex.Unlock()
+			return nil, errors.New(fmt.Sprintf("Could not create lock file '%s': %v", lockFile, err))
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			if err := removeStaleLock(path, info); err != nil {
+				mutex.Unlock()
+				return nil, err
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			holder, _ := os.ReadFile(path)
+			mutex.Unlock()
+			return nil, errors.New(fmt.Sprintf("Locked by another process (%s); remove '%s' if it is not running anymore", trimNewline(string(holder)), lockFile))
+		}
+		time.Sleep(time.Duration(20+rand.Intn(80)) * time.Millisecond)
+	}
+}
+
+// Remove the stale lock file at path, whose state was read as info. Other
+// waiters may have found it stale as well, and one of them may have
+// replaced it by a fresh lock meanwhile; therefore the file is moved away
+// to a unique name first, and only removed if it is the stale one (same
+// file, unchanged modification time). A fresh lock moved by mistake is
+// moved back; if the path was taken again meanwhile, the fresh lock is kept
+// at the unique name and an error is returned, as the lock can not be
+// trusted anymore.
+func removeStaleLock(path string, info os.FileInfo) error {
+	moved := fmt.Sprintf("%s.stale-%d-%d", path, os.Getpid(), rand.Int63())
+	if err := os.Rename(path, moved); err != nil {
+		// removed or moved by another waiter
+		return nil
+	}
+	if movedInfo, err := os.Stat(moved); err == nil && os.SameFile(info, movedInfo) && movedInfo.ModTime().Equal(info.ModTime()) {
+		os.Remove(moved)
+		return nil
+	}
+
+	if err := os.Link(moved, path); err != nil {
+		return errors.New(fmt.Sprintf("Could not restore lock file '%s' (moved to '%s') while removing a stale lock: %v", path, moved, err))
+	}
+	os.Remove(moved)
+
+	return nil
+}
+
+func trimNewline(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '\n' {
+		return s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLockRemovesStaleLock(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "lock")
+	if err := os.WriteFile(lockFile, []byte("pid 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(lockFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := AcquireLock(lockFile, time.Second)
+	if err != nil {
+		t.Fatalf("stale lock was not removed: %v", err)
+	}
+	defer unlock()
+	if info, err := os.Stat(lockFile); err != nil || time.Since(info.ModTime()) > staleLockAge {
+		t.Errorf("expected a fresh lock file, got %v, %v", info, err)
+	}
+}
+
+func TestRemoveStaleLockKeepsFreshLock(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "lock")
+	if err := os.WriteFile(lockFile, []byte("stale\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(lockFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+	staleInfo, err := os.Stat(lockFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another waiter replaced the stale lock by a fresh one meanwhile
+	os.Remove(lockFile)
+	if err := os.WriteFile(lockFile, []byte("fresh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeStaleLock(lockFile, staleInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(lockFile)
+	if err != nil || string(content) != "fresh\n" {
+		t.Errorf("fresh lock was removed: %q, %v", content, err)
+	}
+	leftovers, _ := filepath.Glob(lockFile + ".stale-*")
+	if len(leftovers) > 0 {
+		t.Errorf("moved lock files left behind: %v", leftovers)
+	}
+}
+
+func TestAcquireLockExcludesGoroutines(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "lock")
+	unlock, err := AcquireLock(lockFile, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AcquireLock(lockFile, 50*time.Millisecond); err == nil {
+		t.Error("lock was acquired twice")
+	}
+	unlock()
+	unlock2, err := AcquireLock(lockFile, time.Second)
+	if err != nil {
+		t.Fatalf("lock not acquirable after release: %v", err)
+	}
+	unlock2()
+}
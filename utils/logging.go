@@ -4,18 +4,98 @@ Copyright © 2023 Martin Loesch <development@martinloesch.net>
 package utils
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"os"
+	"strings"
 )
 
-// Create and returns a new logger object, depending on flag
-// verbose either with normal output or discarding all messages.
-func SetupLogger(verbose bool) *log.Logger {
-	logger := log.Default()
-	if !verbose {
-		logger.SetFlags(0)
-		logger.SetOutput(io.Discard)
+// Supported values for the level of log messages.
+var SupportedLogLevels = []string{"debug", "info", "warn", "error"}
+
+// Supported formats of log messages.
+var SupportedLogFormats = []string{"text", "json"}
+
+// Options for the logger created by SetupLogger.
+type LogOptions struct {
+	// Number of -v flags: 0 logs warnings and errors, 1 also info, 2 also
+	// debug messages.
+	Verbosity int
+	// Explicitly selected level (one of SupportedLogLevels), overriding
+	// Verbosity if set.
+	Level string
+	// Format of the messages, one of SupportedLogFormats (default: text).
+	Format string
+	// File the messages are appended to; if empty, they go to stderr.
+	File string
+}
+
+type loggerKey struct{}
+
+// Create a new logger with the level, format and output given by the
+// options.
+func SetupLogger(options LogOptions) (*slog.Logger, error) {
+	level, err := logLevel(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var out io.Writer = os.Stderr
+	if len(options.File) > 0 {
+		f, err := os.OpenFile(options.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(options.Format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(out, handlerOptions)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(out, handlerOptions)), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported log format '%s', must be one of: %v", options.Format, SupportedLogFormats))
+	}
+}
+
+func logLevel(options LogOptions) (slog.Level, error) {
+	if len(options.Level) > 0 {
+		var level slog.Level
+		err := level.UnmarshalText([]byte(options.Level))
+		if err != nil {
+			return level, errors.New(fmt.Sprintf("Unsupported log level '%s', must be one of: %v", options.Level, SupportedLogLevels))
+		}
+		return level, nil
+	}
+
+	switch {
+	case options.Verbosity <= 0:
+		return slog.LevelWarn, nil
+	case options.Verbosity == 1:
+		return slog.LevelInfo, nil
+	default:
+		return slog.LevelDebug, nil
+	}
+}
+
+// Get a copy of the context carrying the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Get the logger carried by the context; without one, all messages are
+// discarded.
+func Logger(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+			return logger
+		}
 	}
 
-	return logger
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
@@ -4,9 +4,9 @@ Copyright © 2023 Martin Loesch <development@martinloesch.net>
 package utils
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/yuin/goldmark"
@@ -25,10 +25,16 @@ type MarkdownDoc struct {
 // Takes the filename as parameter, returns either a MarkdownDoc object
 // reference or an error.
 func OpenMarkdownFile(filename string) (*MarkdownDoc, error) {
-	data, err := os.ReadFile(filename)
+	data, err := ReadFile(filename)
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Could not read data from Markdown '%s': %v", filename, err))
 	}
+
+	return ParseMarkdown(data)
+}
+
+// Parse markdown text for further processing.
+func ParseMarkdown(data []byte) (*MarkdownDoc, error) {
 	md := goldmark.New(goldmark.WithParserOptions(parser.WithBlockParsers()))
 	doc := md.Parser().Parse(text.NewReader(data))
 	res := MarkdownDoc{Source: data, Doc: doc}
@@ -64,3 +70,227 @@ func (doc *MarkdownDoc) FindInsertAtEndOfSection(sectionName string) (preText st
 
 	return string(doc.Source[:cutPoint]), string(doc.Source[cutPoint:])
 }
+
+// A link in a markdown document.
+type MarkdownLink struct {
+	Destination string
+	// Text of the link, or the alternative text of an image.
+	Text string
+	// Line of the link in the document (starting at 1), 0 if unknown.
+	Line int
+	// Text of the line before the link, e.g. "Superseded by ".
+	Prefix string
+}
+
+// Find all links (including images) in the markdown document.
+func (doc *MarkdownDoc) FindLinks() []MarkdownLink {
+	res := make([]MarkdownLink, 0)
+	mdast.Walk(doc.Doc, func(n mdast.Node, entering bool) (mdast.WalkStatus, error) {
+		if !entering {
+			return mdast.WalkContinue, nil
+		}
+		var link MarkdownLink
+		switch node := n.(type) {
+		case *mdast.Link:
+			link.Destination = string(node.Destination)
+		case *mdast.Image:
+			link.Destination = string(node.Destination)
+		default:
+			return mdast.WalkContinue, nil
+		}
+
+		link.Text = doc.plainText(n)
+		if start := firstTextStart(n); start >= 0 {
+			lineStart := bytes.LastIndexByte(doc.Source[:start], '\n') + 1
+			link.Line = bytes.Count(doc.Source[:start], []byte("\n")) + 1
+			// strip the opening bracket of the link or image
+			link.Prefix = strings.TrimSuffix(strings.TrimSuffix(string(doc.Source[lineStart:start]), "["), "!")
+		}
+		res = append(res, link)
+
+		return mdast.WalkSkipChildren, nil
+	})
+
+	return res
+}
+
+// Get the start offset of the first text below node n in the source, or -1.
+func firstTextStart(n mdast.Node) int {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*mdast.Text); ok {
+			return t.Segment.Start
+		}
+		if start := firstTextStart(c); start >= 0 {
+			return start
+		}
+	}
+
+	return -1
+}
+
+// Get the ids of all headings of the markdown file, as generated for
+// anchors (e.g. "## Status" -> "status").
+func FindMarkdownHeadingIds(filename string) (map[string]bool, error) {
+	data, err := ReadFile(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not read data from Markdown '%s': %v", filename, err))
+	}
+	md := goldmark.New(goldmark.WithParserOptions(parser.WithAutoHeadingID()))
+	doc := md.Parser().Parse(text.NewReader(data))
+
+	res := make(map[string]bool)
+	mdast.Walk(doc, func(n mdast.Node, entering bool) (mdast.WalkStatus, error) {
+		if entering && n.Kind() == mdast.KindHeading {
+			if id, ok := n.AttributeString("id"); ok {
+				res[string(id.([]byte))] = true
+			}
+		}
+		return mdast.WalkContinue, nil
+	})
+
+	return res, nil
+}
+
+// A section of a markdown document, see SplitSections.
+type MarkdownSection struct {
+	// Text of the section's heading, empty for the text preceding the
+	// first section.
+	Heading string
+	// Content of the section (without its heading) as markdown.
+	Markdown string
+	// Content of the section as plain text, i.e. without any markup.
+	Text string
+}
+
+// Split the markdown document into the sections started by its headings of
+// the given level. Each section ends at the next heading of the same or a
+// higher level; headings of lower levels are part of the section.
+//
+// Returns the content between the last heading of a higher level preceding
+// the first section and the first section (e.g. the lines below the title
+// of an ADR) as section without heading, and the sections.
+func (doc *MarkdownDoc) SplitSections(level int) (MarkdownSection, []MarkdownSection) {
+	var preamble MarkdownSection
+	sections := make([]MarkdownSection, 0)
+
+	// section whose content is collected, nil for content outside of any
+	// section, and the start of its content
+	current := &preamble
+	start := 0
+	var nodes []mdast.Node
+	finish := func(end int) {
+		if current != nil {
+			current.Markdown = strings.TrimSpace(string(doc.Source[start:end]))
+			current.Text = doc.plainTextOfBlocks(nodes)
+		}
+		nodes = nil
+	}
+
+	for node := doc.Doc.FirstChild(); node != nil; node = node.NextSibling() {
+		heading, ok := node.(*mdast.Heading)
+		if !ok || heading.Level > level || heading.Lines().Len() == 0 {
+			nodes = append(nodes, node)
+			continue
+		}
+		lineStart, lineEnd := doc.headingLine(heading)
+		finish(lineStart)
+		start = lineEnd
+		if heading.Level < level {
+			current = nil
+			if len(sections) == 0 {
+				preamble = MarkdownSection{}
+				current = &preamble
+			}
+			continue
+		}
+		sections = append(sections, MarkdownSection{Heading: doc.plainText(heading)})
+		current = &sections[len(sections)-1]
+	}
+	finish(len(doc.Source))
+
+	return preamble, sections
+}
+
+// Get the start offset of the heading's line in the source, and the offset
+// following it (including the underline of a setext heading).
+func (doc *MarkdownDoc) headingLine(heading *mdast.Heading) (int, int) {
+	lines := heading.Lines()
+	start := bytes.LastIndexByte(doc.Source[:lines.At(0).Start], '\n') + 1
+	end := nextLineStart(doc.Source, lines.At(lines.Len()-1).Stop)
+	if underlineEnd := nextLineStart(doc.Source, end); underlineEnd > end {
+		underline := strings.TrimSpace(string(doc.Source[end:underlineEnd]))
+		if len(underline) > 0 && (strings.Trim(underline, "=") == "" || strings.Trim(underline, "-") == "") {
+			end = underlineEnd
+		}
+	}
+
+	return start, end
+}
+
+// Get the offset of the line following the offset pos in the source, or the
+// length of the source if there is none.
+func nextLineStart(source []byte, pos int) int {
+	if pos >= len(source) {
+		return len(source)
+	}
+	if i := bytes.IndexByte(source[pos:], '\n'); i >= 0 {
+		return pos + i + 1
+	}
+
+	return len(source)
+}
+
+// Get the plain text of a list of blocks, separated by empty lines.
+func (doc *MarkdownDoc) plainTextOfBlocks(nodes []mdast.Node) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if text := strings.TrimSpace(doc.plainText(n)); len(text) > 0 {
+			parts = append(parts, text)
+		}
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// Get the text of node n without any markup: the texts of inline elements
+// are concatenated, those of nested blocks (e.g. list items) are put on
+// lines of their own.
+func (doc *MarkdownDoc) plainText(n mdast.Node) string {
+	switch node := n.(type) {
+	case *mdast.Text:
+		text := string(node.Segment.Value(doc.Source))
+		if node.SoftLineBreak() || node.HardLineBreak() {
+			text += "\n"
+		}
+		return text
+	case *mdast.String:
+		return string(node.Value)
+	case *mdast.AutoLink:
+		return string(node.URL(doc.Source))
+	case *mdast.RawHTML, *mdast.HTMLBlock, *mdast.ThematicBreak:
+		return ""
+	case *mdast.CodeBlock, *mdast.FencedCodeBlock:
+		var sb strings.Builder
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			sb.Write(line.Value(doc.Source))
+		}
+		return strings.TrimSuffix(sb.String(), "\n")
+	}
+
+	parts := make([]string, 0)
+	separator := ""
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.Type() == mdast.TypeBlock {
+			separator = "\n"
+		}
+		parts = append(parts, doc.plainText(c))
+	}
+	if separator == "\n" {
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+	}
+
+	return strings.Join(parts, separator)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2023 Martin Loesch <development@martinloesch.net>
+*/
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Name of the environment variable telling plugins the path of the adr-go
+// executable calling them.
+const PluginExecutableEnv = "ADR_GO_EXECUTABLE"
+
+// Find the executables on PATH whose names start with prefix, e.g.
+// 'adr-go-'. Returns their paths by the rest of their names (on Windows
+// without the extension); of several executables with the same name, the
+// first one on PATH is taken, like the shell does.
+func FindExecutablesWithPrefix(prefix string) map[string]string {
+	res := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if len(dir) == 0 {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := executableName(dir, entry)
+			if !ok || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+				continue
+			}
+			name = name[len(prefix):]
+			if _, found := res[name]; !found {
+				res[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+
+	return res
+}
+
+// Get the name of the directory entry as command, if it is an executable
+// file.
+func executableName(dir string, entry os.DirEntry) (string, bool) {
+	info, err := os.Stat(filepath.Join(dir, entry.Name()))
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" {
+			return "", false
+		}
+		return strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())), true
+	}
+
+	return entry.Name(), info.Mode()&0111 != 0
+}
+
+// Get the environment for running a plugin: the environment of adr-go, and
+// the path of the adr-go executable (see PluginExecutableEnv).
+func PluginEnvironment() []string {
+	env := os.Environ()
+	if self, err := os.Executable(); err == nil {
+		env = append(env, PluginExecutableEnv+"="+self)
+	}
+
+	return env
+}